@@ -2,91 +2,188 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/dharmab/skyeye/pkg/coalitions"
 	"github.com/dharmab/skyeye/pkg/simpleradio"
 	"github.com/dharmab/skyeye/pkg/simpleradio/types"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
 	atcclient "github.com/ErikGoldman/DCSAtcOverhaul/pkg/client"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/commands"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/config"
 	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/deepgramRecognizer"
 	deepgramspeaker "github.com/ErikGoldman/DCSAtcOverhaul/pkg/deepgramSpeaker"
-	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/mission"
+	piperspeaker "github.com/ErikGoldman/DCSAtcOverhaul/pkg/piperSpeaker"
+	speechcache "github.com/ErikGoldman/DCSAtcOverhaul/pkg/speechCache"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/whisperRecognizer"
+	"github.com/dharmab/skyeye/pkg/recognizer"
 	"github.com/dharmab/skyeye/pkg/telemetry"
 )
 
 func main() {
-	telemetryAddress := flag.String("telemetryAddress", "", "The address of the Tacview server")
-	if telemetryAddress == nil {
-		panic("No telemetry address defined")
+	configPath := flag.String("config", "config.yaml", "YAML or JSON configuration file, see config.example.yaml")
+	telemetryAddress := flag.String("telemetryAddress", "", "The address of the Tacview server, overrides telemetry.address")
+	flag.Parse()
+
+	load := config.Load
+	// older installs only have a config.json
+	if _, err := os.Stat(*configPath); os.IsNotExist(err) && *configPath == "config.yaml" {
+		*configPath = "config.json"
+		load = config.LoadLegacy
+	}
+	configData, err := load(*configPath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+	if *telemetryAddress != "" {
+		configData.Telemetry.Address = *telemetryAddress
+	}
+
+	level, _ := zerolog.ParseLevel(configData.Logging.Level)
+	zerolog.SetGlobalLevel(level)
+	if configData.Logging.Format == "console" {
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	}
+
+	var atcMap atcmodel.AtcMap
+	if strings.HasSuffix(configData.Terrain, ".json") {
+		atcMap, err = atcmodel.LoadAtcMap(configData.Terrain)
+	} else if configData.Terrain != "" {
+		atcMap, err = atcmodel.LoadTerrainMap(configData.Terrain)
+	} else {
+		log.Warn().Msg("no terrain configured, airfield lookups will fail")
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load airfield data")
+	}
+	log.Info().Str("terrain", atcMap.Terrain).Int("airfields", len(atcMap.Airfields)).Msg("loaded airfield data")
+
+	atcModel := atcmodel.NewAtcModel(atcMap)
+	frequencies, err := configData.StaffControllers(atcModel)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to staff controllers")
+	}
+	if configData.MissionFile != "" {
+		weather, err := mission.LoadWeather(configData.MissionFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load mission weather")
+		}
+		atcModel.SetWeather(weather)
+		log.Info().Str("mission", configData.MissionFile).Msg("loaded mission weather")
+		// the file may have been swapped for the next mission by the time it starts
+		atcModel.LoadWeather = func() (atcmodel.Weather, error) {
+			return mission.LoadWeather(configData.MissionFile)
+		}
+	} else {
+		log.Warn().Msg("no mission_file configured, reporting standard weather")
 	}
 
-	config := types.ClientConfiguration{
-		Address:                   "192.168.86.40:5002",
-		ClientName:                "test",
-		ExternalAWACSModePassword: "test",
+	coalition, _ := configData.Coalition()
+	srsConfig := types.ClientConfiguration{
+		Address:                   configData.SRS.Address,
+		ClientName:                configData.SRS.ClientName,
+		ExternalAWACSModePassword: configData.SRS.Password,
 		GUID:                      uuid.New().String(),
-		Coalition:                 coalitions.Blue,
+		Coalition:                 coalition,
 		ConnectionTimeout:         10 * time.Second,
 		AllowRecording:            true,
 		Mute:                      false,
-		Radios: []types.Radio{
-			{
-				Frequency:        305000000.0,
-				IsEncrypted:      false,
-				ShouldRetransmit: true,
-				Modulation:       types.ModulationAM,
-			},
-		},
-	}
-
-	// Read and parse the config.json file
-	configFile, err := os.ReadFile("config.json")
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to read config.json")
+		Radios:                    []types.Radio{},
 	}
-
-	var configData struct {
-		Deepgram struct {
-			APIKey string `json:"api_key"`
-		} `json:"deepgram"`
+	for _, frequency := range frequencies {
+		srsConfig.Radios = append(srsConfig.Radios, types.Radio{
+			Frequency:        frequency.Hertz(),
+			IsEncrypted:      false,
+			ShouldRetransmit: true,
+			Modulation:       atcModel.Modulation(frequency),
+		})
 	}
 
-	err = json.Unmarshal(configFile, &configData)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to parse config.json")
+	var speechRecognizer recognizer.Recognizer
+	switch configData.Recognizer {
+	case "deepgram":
+		speechRecognizer = deepgramRecognizer.NewAtcDeepgramRecognizer(configData.Deepgram.APIKey)
+	case "whisper":
+		log.Info().Str("model", configData.Whisper.ModelPath).Msg("using local whisper recognizer")
+		speechRecognizer = whisperRecognizer.NewAtcWhisperRecognizer(configData.Whisper.BinaryPath, configData.Whisper.ModelPath, configData.Whisper.Threads)
 	}
 
-	recognizer := deepgramRecognizer.NewAtcDeepgramRecognizer(configData.Deepgram.APIKey)
+	// each synthesis worker gets its own synthesizer
+	var newSpeechSynthesizer func() deepgramspeaker.TextToSpeech
+	var sampleRate int
+	switch configData.SpeechSynthesizer {
+	case "deepgram":
+		sampleRate = deepgramspeaker.SAMPLE_RATE
+		newSpeechSynthesizer = func() deepgramspeaker.TextToSpeech {
+			return deepgramspeaker.NewSpeechSynthesizer(configData.Deepgram.APIKey)
+		}
+	case "piper":
+		log.Info().Str("voice", configData.Piper.DefaultVoice).Msg("using local piper speech synthesizer")
+		sampleRate = piperspeaker.SAMPLE_RATE
+		newSpeechSynthesizer = func() deepgramspeaker.TextToSpeech {
+			return piperspeaker.NewPiperSpeechSynthesizer(configData.Piper.BinaryPath, configData.Piper.VoicesDir, configData.Piper.DefaultVoice)
+		}
+	}
+	if configData.SpeechCache.Dir != "" {
+		cache, err := speechcache.NewCache(configData.SpeechCache.Dir, int64(configData.SpeechCache.MaxMegabytes*1024*1024))
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to open speech cache")
+		}
+		newUncachedSpeechSynthesizer := newSpeechSynthesizer
+		newSpeechSynthesizer = func() deepgramspeaker.TextToSpeech {
+			return speechcache.NewCachingSpeechSynthesizer(cache, newUncachedSpeechSynthesizer(), sampleRate)
+		}
+		if configData.SpeechCache.Prewarm {
+			go prewarmSpeechCache(speechcache.NewCachingSpeechSynthesizer(cache, newUncachedSpeechSynthesizer(), sampleRate), atcModel)
+		}
+	}
 
 	var telemetryClient telemetry.Client
-	log.Info().Str("address", *telemetryAddress).Msg("constructing telemetry client")
+	log.Info().Str("address", configData.Telemetry.Address).Msg("constructing telemetry client")
 	telemetryClient = telemetry.NewTelemetryClient(
-		*telemetryAddress,
-		"hostname",
-		"",            // password
+		configData.Telemetry.Address,
+		configData.Telemetry.Hostname,
+		configData.Telemetry.Password,
 		500,           //timeout
 		2*time.Second, // refresh rate in seconds
 	)
 
+	commandProcessor := atcclient.LoadCommandProcessor()
+	// pilots calling the wrong controller are told who to contact
+	commandProcessor.SetMap(&atcModel.Map)
+	if configData.LLM.Endpoint != "" {
+		log.Info().Str("endpoint", configData.LLM.Endpoint).Str("model", configData.LLM.Model).Msg("classifying calls with LLM")
+		commandProcessor.RegisterParser(commands.NewLLMIntentParser(configData.LLM.Endpoint, configData.LLM.Model,
+			configData.LLM.APIKey, time.Duration(configData.LLM.TimeoutSeconds)*time.Second, atcModel.LatestSnapshot,
+			atcclient.RuleParsers()...))
+	}
+
 	a := &atcclient.AtcApplication{
-		Recognizer:                 recognizer,
-		EnableTranscriptionLogging: true,
-		TranscribedMessages:        make(chan message.Message[string]),
-		CommandProcessor:           atcclient.LoadCommandProcessor(),
-		SpeechSynthesizer:          deepgramspeaker.NewSpeechSynthesizer(configData.Deepgram.APIKey),
+		Recognizer:                 speechRecognizer,
+		EnableTranscriptionLogging: configData.Logging.Transcripts,
+		CommandProcessor:           commandProcessor,
+		NewSpeechSynthesizer:       newSpeechSynthesizer,
 		TelemetryClient:            telemetryClient,
+		AtcModel:                   atcModel,
+		RecognitionWorkers:         configData.Workers.Recognition,
+		ParsingWorkers:             configData.Workers.Parsing,
+		SynthesisWorkers:           configData.Workers.Synthesis,
+		QuietGap:                   time.Duration(configData.Transmit.QuietGapSeconds * float64(time.Second)),
+		MaxTransmitDelay:           time.Duration(configData.Transmit.MaxDelaySeconds * float64(time.Second)),
 	}
 
-	log.Info().Msgf("config: %v", config)
+	log.Info().Str("address", srsConfig.Address).Str("name", srsConfig.ClientName).Int("radios", len(srsConfig.Radios)).
+		Msg("connecting to SRS")
 
-	srsClient, err := simpleradio.NewClient(config)
+	srsClient, err := simpleradio.NewClient(srsConfig)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to create SRS client")
 		return
@@ -104,3 +201,21 @@ func main() {
 
 	log.Info().Msgf("done")
 }
+
+// prewarmSpeechCache synthesizes the common phrases and controller names in each controller's voice, so the
+// first calls of the session are as quick as the rest
+func prewarmSpeechCache(synthesizer *speechcache.CachingSpeechSynthesizer, atcModel *atcmodel.AtcModel) {
+	phrases := map[string][]string{}
+	voices := []string{}
+	for _, controller := range atcModel.Controllers {
+		if _, ok := phrases[controller.Voice]; !ok {
+			voices = append(voices, controller.Voice)
+			phrases[controller.Voice] = append([]string{}, speechcache.COMMON_FRAGMENTS...)
+		}
+		phrases[controller.Voice] = append(phrases[controller.Voice], controller.Name())
+	}
+	for _, voice := range voices {
+		synthesizer.Warm(voice, phrases[voice])
+	}
+	log.Info().Msg("speech cache is warm")
+}
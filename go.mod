@@ -7,8 +7,11 @@ require (
 	github.com/deepgram/deepgram-go-sdk v1.6.0
 	github.com/dharmab/skyeye v0.13.1
 	github.com/google/uuid v1.6.0
+	github.com/martinlindhe/unit v0.0.0-20230420213220-4adfd7d0a0d6
+	github.com/paulmach/orb v0.11.1
 	github.com/rs/zerolog v1.33.0
 	github.com/stretchr/testify v1.10.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 replace github.com/dharmab/skyeye => github.com/ErikGoldman/skyeye v0.0.0-20241127154959-194a8f3a08d2
@@ -24,10 +27,8 @@ require (
 	github.com/hokaccha/go-prettyjson v0.0.0-20211117102719-0474bc63780f // indirect
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/lithammer/shortuuid/v3 v3.0.7 // indirect
-	github.com/martinlindhe/unit v0.0.0-20230420213220-4adfd7d0a0d6 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	github.com/paulmach/orb v0.11.1 // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/proway2/go-igrf v0.5.1 // indirect
 	github.com/rogpeppe/go-internal v1.13.1 // indirect
@@ -35,6 +36,5 @@ require (
 	golang.org/x/sys v0.27.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/hraban/opus.v2 v2.0.0-20230925203106-0188a62cb302 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	k8s.io/klog/v2 v2.110.1 // indirect
 )
@@ -0,0 +1,47 @@
+package atcmodel
+
+import (
+	"github.com/martinlindhe/unit"
+	"github.com/rs/zerolog/log"
+)
+
+// another runway needs at least this much more headwind before we switch to it, so a wind straight across
+// the runway doesn't flip the active back and forth
+const RUNWAY_CHANGE_MARGIN = 2 * unit.Knot
+
+// SetWeather updates the mission weather and picks the active runway at every airfield for the new wind
+func (a *AtcModel) SetWeather(weather Weather) {
+	a.Weather = weather
+	for _, airfield := range a.Map.Airfields {
+		previous := airfield.ActiveRunway()
+		active := airfield.SelectActiveRunway(a.WeatherAt(airfield))
+		if active != previous {
+			log.Info().Msgf("%s now using runway %s, %s", airfield.Name, active.Designator,
+				a.WeatherAt(airfield).WindPhrase(a.Declination(airfield)))
+		}
+	}
+}
+
+// SelectActiveRunway makes the runway end with the most headwind active. The current runway stays in use when
+// the wind is calm or no other runway is clearly better.
+func (a *Airfield) SelectActiveRunway(weather Weather) *RunwayEnd {
+	current := a.ActiveRunway()
+	if current == nil || weather.WindSpeed < CALM_WIND_SPEED {
+		return current
+	}
+
+	best := current
+	bestHeadwind := weather.HeadwindComponent(current.Heading) + RUNWAY_CHANGE_MARGIN
+	for i := range a.Runways {
+		for j := range a.Runways[i].Ends {
+			end := &a.Runways[i].Ends[j]
+			if headwind := weather.HeadwindComponent(end.Heading); headwind > bestHeadwind {
+				best = end
+				bestHeadwind = headwind
+			}
+		}
+	}
+
+	a.SetActiveRunway(best)
+	return best
+}
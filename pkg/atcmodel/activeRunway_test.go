@@ -0,0 +1,59 @@
+package atcmodel
+
+import (
+	"testing"
+
+	"github.com/dharmab/skyeye/pkg/bearings"
+	"github.com/martinlindhe/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+func windFrom(degrees unit.Angle, speed unit.Speed) Weather {
+	weather := StandardWeather()
+	weather.WindDirection = bearings.NewTrueBearing(degrees * unit.Degree)
+	weather.WindSpeed = speed
+	return weather
+}
+
+func TestSetWeather_SelectsActiveRunway(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	model := NewAtcModel(atcMap)
+	anapa := model.Map.AirfieldByName("Anapa")
+	assert.Equal(t, "04", anapa.ActiveRunway().Designator)
+
+	model.SetWeather(windFrom(220, 12*unit.Knot))
+	assert.Equal(t, "22", anapa.ActiveRunway().Designator)
+	assert.Equal(t, "wind 214 at 12", model.WeatherAt(anapa).WindPhrase(6*unit.Degree), "reported magnetic")
+
+	// calm wind keeps whatever's in use
+	model.SetWeather(windFrom(40, 2*unit.Knot))
+	assert.Equal(t, "22", anapa.ActiveRunway().Designator)
+
+	// straight across the runway, a slight tailwind isn't worth switching for
+	model.SetWeather(windFrom(130, 10*unit.Knot))
+	assert.Equal(t, "22", anapa.ActiveRunway().Designator)
+
+	model.SetWeather(windFrom(60, 10*unit.Knot))
+	assert.Equal(t, "04", anapa.ActiveRunway().Designator)
+}
+
+func TestHeadwindComponent(t *testing.T) {
+	weather := windFrom(220, 10*unit.Knot)
+	assert.InDelta(t, 10, weather.HeadwindComponent(bearings.NewTrueBearing(220*unit.Degree)).Knots(), 0.01)
+	assert.InDelta(t, -10, weather.HeadwindComponent(bearings.NewTrueBearing(40*unit.Degree)).Knots(), 0.01)
+	assert.InDelta(t, 5, weather.HeadwindComponent(bearings.NewTrueBearing(280*unit.Degree)).Knots(), 0.01)
+}
+
+func TestWeatherAt_Elevation(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	model := NewAtcModel(atcMap)
+	model.Weather.Temperature = unit.FromCelsius(20)
+
+	// Sochi is at sea level, Vaziani is up around 1500ft
+	assert.Equal(t, "temperature 20", model.WeatherAt(model.Map.AirfieldByName("Sochi")).TemperaturePhrase())
+	vaziani := model.Map.AirfieldByName("Vaziani")
+	assert.InDelta(t, 20-vaziani.Elevation.Feet()*TEMPERATURE_LAPSE_RATE, model.WeatherAt(vaziani).Temperature.Celsius(), 0.01)
+	assert.Less(t, model.WeatherAt(vaziani).Temperature.Celsius(), 17.5)
+}
@@ -0,0 +1,347 @@
+package atcmodel
+
+import (
+	"math"
+	"time"
+
+	"github.com/dharmab/skyeye/pkg/bearings"
+	"github.com/dharmab/skyeye/pkg/spatial"
+	"github.com/dharmab/skyeye/pkg/trackfiles"
+	"github.com/martinlindhe/unit"
+	"github.com/paulmach/orb"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	TAXI_SPEED         = 3 * unit.Knot
+	TAKEOFF_ROLL_SPEED = 40 * unit.Knot
+
+	RUNWAY_HALF_WIDTH   = 30 * unit.Meter
+	HOLD_SHORT_DISTANCE = 300 * unit.Meter
+	LINED_UP_ALIGNMENT  = 20.0 // degrees
+
+	FINAL_ALIGNMENT   = 15.0 // degrees
+	FINAL_DISTANCE    = 6 * unit.NauticalMile
+	FINAL_MAX_AGL     = 2500 * unit.Foot
+	PATTERN_DISTANCE  = 4 * unit.NauticalMile
+	PATTERN_MAX_AGL   = 3000 * unit.Foot
+	INBOUND_DISTANCE  = 20 * unit.NauticalMile
+	DEPARTED_DISTANCE = 5 * unit.NauticalMile
+
+	// a plane sitting still this long after landing has shut down at parking
+	PARKED_AFTER = 60 * time.Second
+)
+
+// AircraftState is the PlaneState implementation driven by telemetry. Each frame works out where the plane
+// is relative to the nearest airfield's runways and moves it through the ground and air states.
+type AircraftState struct {
+	planeId uint64
+	atcMap  *AtcMap
+
+	state    FlightState
+	airfield *Airfield
+	runway   *RunwayEnd
+
+	lastFrame    *trackfiles.Frame
+	speed        unit.Speed
+	stoppedSince time.Time
+
+	onTransition StateListener
+}
+
+func NewAircraftState(planeId uint64, atcMap *AtcMap, onTransition StateListener) *AircraftState {
+	return &AircraftState{
+		planeId:      planeId,
+		atcMap:       atcMap,
+		state:        StateUnknown,
+		onTransition: onTransition,
+	}
+}
+
+func (s *AircraftState) State() FlightState {
+	return s.state
+}
+
+func (s *AircraftState) Airfield() *Airfield {
+	return s.airfield
+}
+
+func (s *AircraftState) Runway() *RunwayEnd {
+	return s.runway
+}
+
+// GroundSpeed is the speed between the last two frames
+func (s *AircraftState) GroundSpeed() unit.Speed {
+	return s.speed
+}
+
+func (s *AircraftState) TransitionToState(state FlightState) {
+	if state == s.state {
+		return
+	}
+
+	transition := StateTransition{
+		PlaneId:  s.planeId,
+		From:     s.state,
+		To:       state,
+		Airfield: s.airfield,
+		Runway:   s.runway,
+	}
+	if s.lastFrame != nil {
+		transition.Frame = *s.lastFrame
+	}
+
+	log.Info().Msgf("plane %d transitioning from %s to %s", s.planeId, s.state, state)
+	s.state = state
+	if s.onTransition != nil {
+		s.onTransition(transition)
+	}
+}
+
+func (s *AircraftState) UpdateFromTrack(update trackfiles.Frame) {
+	speed := s.groundSpeed(update)
+	s.lastFrame = &update
+	s.speed = speed
+
+	if speed < TAXI_SPEED {
+		if s.stoppedSince.IsZero() {
+			s.stoppedSince = update.Time
+		}
+	} else {
+		s.stoppedSince = time.Time{}
+	}
+
+	airfield, distance := s.atcMap.NearestAirfield(update.Point)
+	if airfield == nil {
+		// nothing to be relative to, we can only tell if it's flying
+		if isAirborne(update, nil) {
+			s.TransitionToState(StateEnroute)
+		}
+		return
+	}
+
+	var next FlightState
+	if isAirborne(update, airfield) {
+		next = s.nextAirborneState(update, airfield, distance)
+	} else {
+		next = s.nextGroundState(update, airfield, speed)
+	}
+
+	s.TransitionToState(next)
+}
+
+func (s *AircraftState) groundSpeed(update trackfiles.Frame) unit.Speed {
+	if s.lastFrame == nil {
+		return 0
+	}
+	elapsed := update.Time.Sub(s.lastFrame.Time)
+	if elapsed <= 0 {
+		return 0
+	}
+	distance := spatial.Distance(s.lastFrame.Point, update.Point)
+	return unit.Speed(distance.Meters()/elapsed.Seconds()) * unit.MetersPerSecond
+}
+
+func isAirborne(update trackfiles.Frame, airfield *Airfield) bool {
+	return aboveGroundLevel(update, airfield) > IS_AIRBORN_AGL
+}
+
+// telemetry doesn't always include AGL, in which case the airfield elevation is close enough
+func aboveGroundLevel(update trackfiles.Frame, airfield *Airfield) unit.Length {
+	if update.AGL != nil {
+		return *update.AGL
+	}
+	if airfield != nil {
+		return update.Altitude - airfield.Elevation
+	}
+	return update.Altitude
+}
+
+func (s *AircraftState) nextGroundState(update trackfiles.Frame, airfield *Airfield, speed unit.Speed) FlightState {
+	heading := bearings.NewTrueBearing(update.Heading)
+	onRunway := airfield.RunwayAt(update.Point)
+
+	// touch and go, or the plane spawned on the ground at a different field
+	if s.state.IsAirborne() {
+		s.airfield = airfield
+		if onRunway != nil {
+			s.runway = onRunway.EndByHeading(heading)
+		}
+		return StateLanded
+	}
+	s.airfield = airfield
+
+	if onRunway != nil {
+		end := onRunway.EndByHeading(heading)
+		aligned := HeadingDifference(end.Heading, heading) <= LINED_UP_ALIGNMENT
+
+		switch {
+		case s.state == StateLanded:
+			// still rolling out or stopped on the runway after landing
+			return StateLanded
+		case speed >= TAKEOFF_ROLL_SPEED && aligned:
+			s.runway = end
+			return StateTakeoffRoll
+		case s.state == StateTakeoffRoll:
+			// rejected takeoff, still on the runway
+			return StateTakeoffRoll
+		case speed < TAXI_SPEED && aligned:
+			s.runway = end
+			return StateLinedUp
+		case s.state == StateLinedUp && aligned:
+			// rolling slowly before the throttle comes up
+			return StateLinedUp
+		default:
+			// crossing or back taxiing
+			s.runway = nil
+			return StateTaxiing
+		}
+	}
+
+	switch s.state {
+	case StateLanded, StateVacated:
+		if !s.stoppedSince.IsZero() && update.Time.Sub(s.stoppedSince) >= PARKED_AFTER {
+			s.runway = nil
+			return StateParked
+		}
+		return StateVacated
+	case StateTakeoffRoll, StateLinedUp:
+		// taxied off the runway again
+		s.runway = nil
+		return StateTaxiing
+	}
+
+	if speed < TAXI_SPEED {
+		// some parking spots are close to a runway end, so only planes that taxied there are holding short
+		isTaxiing := s.state == StateTaxiing || s.state == StateHoldingShort
+		if end := airfield.RunwayEndNear(update.Point, HOLD_SHORT_DISTANCE); end != nil && isTaxiing {
+			s.runway = end
+			return StateHoldingShort
+		}
+		if s.state == StateUnknown || s.state == StateParked {
+			return StateParked
+		}
+		// stopped on a taxiway
+		return StateTaxiing
+	}
+
+	s.runway = nil
+	return StateTaxiing
+}
+
+func (s *AircraftState) nextAirborneState(update trackfiles.Frame, airfield *Airfield, distance unit.Length) FlightState {
+	agl := aboveGroundLevel(update, airfield)
+
+	switch s.state {
+	case StateTakeoffRoll, StateLinedUp, StateHoldingShort, StateTaxiing, StateLanded:
+		s.airfield = airfield
+		return StateDeparting
+	case StateDeparting:
+		if s.airfield != nil && spatial.Distance(update.Point, s.airfield.Position) < DEPARTED_DISTANCE {
+			return StateDeparting
+		}
+	}
+
+	heading := bearings.NewTrueBearing(update.Heading)
+	if agl <= FINAL_MAX_AGL {
+		if end := airfield.FinalApproachEnd(update.Point, heading); end != nil {
+			s.airfield = airfield
+			s.runway = end
+			return StateOnFinal
+		}
+	}
+
+	s.runway = nil
+	if distance <= PATTERN_DISTANCE && agl <= PATTERN_MAX_AGL {
+		s.airfield = airfield
+		return StateInPattern
+	}
+	if distance <= INBOUND_DISTANCE && s.isClosing(update, airfield) {
+		s.airfield = airfield
+		return StateInbound
+	}
+	if s.state == StateInbound && distance <= INBOUND_DISTANCE {
+		// holding or maneuvering near the field
+		return StateInbound
+	}
+
+	s.airfield = nil
+	return StateEnroute
+}
+
+func (s *AircraftState) isClosing(update trackfiles.Frame, airfield *Airfield) bool {
+	toAirfield := spatial.TrueBearing(update.Point, airfield.Position)
+	return HeadingDifference(toAirfield, bearings.NewTrueBearing(update.Heading)) < 90
+}
+
+// alongAndCross returns how far the point is along the runway from the first end's threshold, and how far
+// off the centerline it is
+func (r *Runway) alongAndCross(point orb.Point) (unit.Length, unit.Length) {
+	start := r.Ends[0]
+	distance := spatial.Distance(start.Threshold, point)
+	if distance == 0 {
+		return 0, 0
+	}
+	offset := (spatial.TrueBearing(start.Threshold, point).Degrees() - start.Heading.Degrees()) * math.Pi / 180
+	return distance * unit.Length(math.Cos(offset)), distance * unit.Length(math.Abs(math.Sin(offset)))
+}
+
+func (r *Runway) Contains(point orb.Point) bool {
+	along, cross := r.alongAndCross(point)
+	return along >= 0 && along <= r.Length && cross <= RUNWAY_HALF_WIDTH
+}
+
+// EndByHeading returns the end of this runway that a plane on the given heading is rolling towards
+func (r *Runway) EndByHeading(heading bearings.Bearing) *RunwayEnd {
+	if HeadingDifference(r.Ends[0].Heading, heading) <= 90 {
+		return &r.Ends[0]
+	}
+	return &r.Ends[1]
+}
+
+// RunwayAt returns the runway the point is on, if any
+func (a *Airfield) RunwayAt(point orb.Point) *Runway {
+	for i := range a.Runways {
+		if a.Runways[i].Contains(point) {
+			return &a.Runways[i]
+		}
+	}
+	return nil
+}
+
+// RunwayEndNear returns the closest runway end whose threshold is within maxDistance of the point
+func (a *Airfield) RunwayEndNear(point orb.Point, maxDistance unit.Length) *RunwayEnd {
+	var nearest *RunwayEnd
+	nearestDistance := maxDistance
+	for i := range a.Runways {
+		for j := range a.Runways[i].Ends {
+			end := &a.Runways[i].Ends[j]
+			if distance := spatial.Distance(point, end.Threshold); distance <= nearestDistance {
+				nearest = end
+				nearestDistance = distance
+			}
+		}
+	}
+	return nearest
+}
+
+// FinalApproachEnd returns the runway end the plane is lined up to land on, if it's on the extended centerline
+func (a *Airfield) FinalApproachEnd(point orb.Point, heading bearings.Bearing) *RunwayEnd {
+	for i := range a.Runways {
+		for j := range a.Runways[i].Ends {
+			end := &a.Runways[i].Ends[j]
+			if HeadingDifference(end.Heading, heading) > FINAL_ALIGNMENT {
+				continue
+			}
+			if spatial.Distance(point, end.Threshold) > FINAL_DISTANCE {
+				continue
+			}
+			toThreshold := spatial.TrueBearing(point, end.Threshold)
+			if HeadingDifference(toThreshold, end.Heading) > FINAL_ALIGNMENT {
+				continue
+			}
+			return end
+		}
+	}
+	return nil
+}
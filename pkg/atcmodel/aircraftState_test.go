@@ -0,0 +1,155 @@
+package atcmodel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dharmab/skyeye/pkg/bearings"
+	"github.com/dharmab/skyeye/pkg/spatial"
+	"github.com/dharmab/skyeye/pkg/trackfiles"
+	"github.com/martinlindhe/unit"
+	"github.com/paulmach/orb"
+	"github.com/stretchr/testify/assert"
+)
+
+type frameStep struct {
+	name     string
+	point    orb.Point
+	heading  unit.Angle
+	agl      unit.Length
+	elapsed  time.Duration
+	expected FlightState
+}
+
+func pointFrom(origin orb.Point, heading unit.Angle, distance unit.Length) orb.Point {
+	return spatial.PointAtBearingAndDistance(origin, bearings.NewTrueBearing(heading), distance)
+}
+
+func runSteps(t *testing.T, state *AircraftState, steps []frameStep) {
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	for _, step := range steps {
+		now = now.Add(step.elapsed)
+		state.UpdateFromTrack(trackfiles.Frame{
+			Time:    now,
+			Point:   step.point,
+			Heading: step.heading,
+			AGL:     ptrlength(step.agl),
+		})
+		assert.Equal(t, step.expected, state.State(), step.name)
+	}
+}
+
+func TestAircraftState_DepartureAndArrival(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+
+	anapa := atcMap.AirfieldByName("Anapa")
+	runway04 := anapa.RunwayEndByDesignator("04")
+	runway22 := anapa.RunwayEndByDesignator("22")
+
+	transitions := []StateTransition{}
+	state := NewAircraftState(1, &atcMap, func(transition StateTransition) {
+		transitions = append(transitions, transition)
+	})
+
+	// parking off to the side of the runway, away from either end
+	parking := pointFrom(anapa.Position, 132*unit.Degree, 400*unit.Meter)
+	holdShort := pointFrom(runway04.Threshold, 132*unit.Degree, 150*unit.Meter)
+	takeoffRoll := pointFrom(runway04.Threshold, 42*unit.Degree, 500*unit.Meter)
+	liftoff := pointFrom(runway04.Threshold, 42*unit.Degree, 1500*unit.Meter)
+	departure := pointFrom(anapa.Position, 42*unit.Degree, 8*unit.NauticalMile)
+	faraway := pointFrom(anapa.Position, 42*unit.Degree, 40*unit.NauticalMile)
+	inbound := pointFrom(anapa.Position, 270*unit.Degree, 15*unit.NauticalMile)
+	pattern := pointFrom(anapa.Position, 132*unit.Degree, 2*unit.NauticalMile)
+	final := pointFrom(runway22.Threshold, 42*unit.Degree, 3*unit.NauticalMile)
+	touchdown := pointFrom(runway22.Threshold, 222*unit.Degree, 300*unit.Meter)
+	rollout := pointFrom(runway22.Threshold, 222*unit.Degree, 600*unit.Meter)
+	vacated := pointFrom(rollout, 312*unit.Degree, 100*unit.Meter)
+
+	runSteps(t, state, []frameStep{
+		{name: "spawned at parking", point: parking, heading: 0, expected: StateParked},
+		{name: "still parked", point: parking, heading: 0, elapsed: 2 * time.Second, expected: StateParked},
+		{name: "taxiing", point: pointFrom(parking, 222*unit.Degree, 20*unit.Meter), heading: 222 * unit.Degree, elapsed: 2 * time.Second, expected: StateTaxiing},
+		{name: "holding short", point: holdShort, heading: 312 * unit.Degree, elapsed: 2 * time.Minute, expected: StateTaxiing},
+		{name: "stopped at hold short", point: holdShort, heading: 312 * unit.Degree, elapsed: 2 * time.Second, expected: StateHoldingShort},
+		{name: "lined up", point: runway04.Threshold, heading: 42 * unit.Degree, elapsed: time.Minute, expected: StateTaxiing},
+		{name: "stopped on runway", point: runway04.Threshold, heading: 42 * unit.Degree, elapsed: 2 * time.Second, expected: StateLinedUp},
+		{name: "takeoff roll", point: takeoffRoll, heading: 42 * unit.Degree, elapsed: 10 * time.Second, expected: StateTakeoffRoll},
+		{name: "airborne", point: liftoff, heading: 42 * unit.Degree, agl: 50 * unit.Foot, elapsed: 10 * time.Second, expected: StateDeparting},
+		{name: "climbing out", point: pointFrom(liftoff, 42*unit.Degree, 2*unit.NauticalMile), heading: 42 * unit.Degree, agl: 2000 * unit.Foot, elapsed: 30 * time.Second, expected: StateDeparting},
+		{name: "left the area", point: departure, heading: 42 * unit.Degree, agl: 5000 * unit.Foot, elapsed: time.Minute, expected: StateEnroute},
+		{name: "far away", point: faraway, heading: 42 * unit.Degree, agl: 20000 * unit.Foot, elapsed: 5 * time.Minute, expected: StateEnroute},
+		{name: "inbound", point: inbound, heading: 90 * unit.Degree, agl: 5000 * unit.Foot, elapsed: 10 * time.Minute, expected: StateInbound},
+		{name: "in pattern", point: pattern, heading: 312 * unit.Degree, agl: 1500 * unit.Foot, elapsed: 2 * time.Minute, expected: StateInPattern},
+		{name: "on final", point: final, heading: 222 * unit.Degree, agl: 900 * unit.Foot, elapsed: time.Minute, expected: StateOnFinal},
+		{name: "touchdown", point: touchdown, heading: 222 * unit.Degree, elapsed: time.Minute, expected: StateLanded},
+		{name: "rollout", point: rollout, heading: 222 * unit.Degree, elapsed: 5 * time.Second, expected: StateLanded},
+		{name: "vacated", point: vacated, heading: 312 * unit.Degree, elapsed: 20 * time.Second, expected: StateVacated},
+		{name: "stopped after vacating", point: vacated, heading: 312 * unit.Degree, elapsed: 2 * time.Second, expected: StateVacated},
+		{name: "shut down", point: vacated, heading: 312 * unit.Degree, elapsed: 2 * time.Minute, expected: StateParked},
+	})
+
+	assert.Equal(t, StateHoldingShort, transitions[2].To)
+	assert.Equal(t, runway04, transitions[2].Runway, "holding short of 04")
+	assert.Equal(t, StateLinedUp, transitions[4].To)
+	assert.Equal(t, runway04, transitions[4].Runway, "lined up on 04")
+	assert.Equal(t, anapa, transitions[4].Airfield)
+
+	for _, transition := range transitions {
+		if transition.To == StateOnFinal {
+			assert.Equal(t, runway22, transition.Runway)
+		}
+		assert.Equal(t, uint64(1), transition.PlaneId)
+	}
+}
+
+func TestAircraftState_SpawnedAirborne(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	anapa := atcMap.AirfieldByName("Anapa")
+
+	state := NewAircraftState(1, &atcMap, nil)
+	runSteps(t, state, []frameStep{
+		{name: "spawned far away", point: pointFrom(anapa.Position, 0, 50*unit.NauticalMile), heading: 0, agl: 10000 * unit.Foot, expected: StateEnroute},
+	})
+
+	// spawned on the runway, ready to go
+	state = NewAircraftState(2, &atcMap, nil)
+	runSteps(t, state, []frameStep{
+		{name: "spawned lined up", point: anapa.RunwayEndByDesignator("22").Threshold, heading: 222 * unit.Degree, expected: StateLinedUp},
+	})
+}
+
+func TestAircraftState_ForcedTransition(t *testing.T) {
+	transitions := []StateTransition{}
+	state := NewAircraftState(1, &AtcMap{}, func(transition StateTransition) {
+		transitions = append(transitions, transition)
+	})
+
+	state.TransitionToState(StateInbound)
+	state.TransitionToState(StateInbound)
+	assert.Equal(t, StateInbound, state.State())
+	assert.Len(t, transitions, 1)
+	assert.Equal(t, StateUnknown, transitions[0].From)
+}
+
+func TestAtcModel_TracksPlaneStates(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	model := NewAtcModel(atcMap)
+
+	transitions := []StateTransition{}
+	model.OnStateTransition(func(transition StateTransition) {
+		transitions = append(transitions, transition)
+	})
+
+	model.PlaneState(5).UpdateFromTrack(trackfiles.Frame{
+		Time:  time.Now(),
+		Point: pointFrom(atcMap.AirfieldByName("Kobuleti").Position, 160*unit.Degree, 400*unit.Meter),
+		AGL:   ptrlength(0),
+	})
+	assert.Equal(t, StateParked, model.PlaneState(5).State())
+	assert.Len(t, model.PlaneStates, 1)
+	assert.Len(t, transitions, 1)
+	assert.Equal(t, "Kobuleti", transitions[0].Airfield.Name)
+}
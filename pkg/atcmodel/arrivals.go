@@ -0,0 +1,206 @@
+package atcmodel
+
+import (
+	"fmt"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/spatial"
+	"github.com/paulmach/orb"
+	"github.com/rs/zerolog/log"
+)
+
+// planes this far either side of the extended centerline can fly a straight-in
+const STRAIGHT_IN_ALIGNMENT = 30.0 // degrees
+
+type PatternEntry int
+
+const (
+	EntryOverhead PatternEntry = iota
+	EntryStraightIn
+	EntryDownwind
+)
+
+func (e PatternEntry) String() string {
+	switch e {
+	case EntryStraightIn:
+		return "straight-in"
+	case EntryDownwind:
+		return "downwind"
+	default:
+		return "overhead break"
+	}
+}
+
+// ChoosePatternEntry picks straight-in for planes already on the approach side of the runway, otherwise the
+// overhead break
+func ChoosePatternEntry(position orb.Point, runway *RunwayEnd) PatternEntry {
+	fromThreshold := spatial.TrueBearing(runway.Threshold, position)
+	if HeadingDifference(fromThreshold, runway.Heading.Reciprocal()) <= STRAIGHT_IN_ALIGNMENT {
+		return EntryStraightIn
+	}
+	return EntryOverhead
+}
+
+// Arrival is a plane that has called inbound and that the controller is talking down to the runway
+type Arrival struct {
+	PlaneId  uint64
+	Airfield *Airfield
+	Runway   *RunwayEnd
+	Entry    PatternEntry
+	// the pilot's inbound call, everything we say to them goes back the same way
+	Request message.Message[string]
+	// what the pilot called themselves, defaults to their SRS name
+	Callsign string
+
+	ClearedToLand bool
+	// place in the landing sequence we last told the pilot, 0 if we haven't yet
+	Number int
+	// the plane we told them to follow
+	following uint64
+}
+
+func (arrival *Arrival) callsign() string {
+	if arrival.Callsign != "" {
+		return arrival.Callsign
+	}
+	return arrival.Request.ClientName
+}
+
+// AddArrival starts following a plane to the runway. If the plane was still enroute it's moved to inbound.
+func (a *AtcModel) AddArrival(arrival *Arrival) {
+	a.Arrivals[arrival.PlaneId] = arrival
+	if state := a.PlaneState(arrival.PlaneId); state.State() == StateEnroute {
+		state.TransitionToState(StateInbound)
+	}
+}
+
+// LandingNumber is the plane's place in line for its runway. Planes that aren't sequenced yet count the planes
+// on final closer to the threshold.
+func (a *AtcModel) LandingNumber(planeId uint64, runway *RunwayEnd) int {
+	for i, sequenced := range a.Sequences[runway] {
+		if sequenced == planeId {
+			return i + 1
+		}
+	}
+
+	plane, ok := a.AllPlaneData[planeId]
+	if !ok {
+		return 1
+	}
+	distance := spatial.Distance(plane.Frame.Point, runway.Threshold)
+
+	number := 1
+	for otherId, state := range a.PlaneStates {
+		if otherId == planeId || state.State() != StateOnFinal || state.Runway() != runway {
+			continue
+		}
+		other, ok := a.AllPlaneData[otherId]
+		if ok && spatial.Distance(other.Frame.Point, runway.Threshold) < distance {
+			number++
+		}
+	}
+	return number
+}
+
+// onArrivalTransition makes the controller's unprompted calls as arrivals move through the pattern
+func (a *AtcModel) onArrivalTransition(transition StateTransition) {
+	arrival, ok := a.Arrivals[transition.PlaneId]
+	if !ok {
+		return
+	}
+	callsign := arrival.callsign()
+
+	switch transition.To {
+	case StateInPattern:
+		arrival.ClearedToLand = false
+		switch arrival.Entry {
+		case EntryOverhead:
+			a.sayToArrival(arrival, fmt.Sprintf("%s, report initial runway %s", callsign, arrival.Runway.Designator))
+		case EntryDownwind:
+			a.sayToArrival(arrival, fmt.Sprintf("%s, report downwind runway %s", callsign, arrival.Runway.Designator))
+		}
+
+	case StateOnFinal:
+		if transition.Runway != arrival.Runway {
+			a.warnArrival(arrival, fmt.Sprintf("%s, go around, runway %s in use", callsign, arrival.Runway.Designator))
+			return
+		}
+		if a.runwayOccupied(arrival) {
+			// cleared by ClearWaitingArrivals once it's empty
+			a.sayToArrival(arrival, fmt.Sprintf("%s, runway %s, continue, traffic on the runway", callsign, arrival.Runway.Designator))
+			return
+		}
+		a.clearToLand(arrival)
+
+	case StateVacated:
+		a.sayToArrival(arrival, fmt.Sprintf("%s, taxi to parking", callsign))
+		delete(a.Arrivals, arrival.PlaneId)
+
+	case StateParked, StateEnroute:
+		// shut down on the runway, or left without landing
+		delete(a.Arrivals, arrival.PlaneId)
+	}
+}
+
+// ClearWaitingArrivals clears arrivals we told to continue once the runway is empty. Runs on every telemetry
+// update.
+func (a *AtcModel) ClearWaitingArrivals() {
+	for planeId, arrival := range a.Arrivals {
+		if arrival.ClearedToLand {
+			continue
+		}
+		state, ok := a.PlaneStates[planeId]
+		if !ok || state.State() != StateOnFinal || state.Runway() != arrival.Runway || a.runwayOccupied(arrival) {
+			continue
+		}
+		a.clearToLand(arrival)
+	}
+}
+
+func (a *AtcModel) clearToLand(arrival *Arrival) {
+	arrival.ClearedToLand = true
+	// the rest of the traffic may not have been updated since this plane turned final
+	a.Resequence()
+	weather := a.WeatherAt(arrival.Airfield)
+	messageText := fmt.Sprintf("%s, runway %s, cleared to land", arrival.callsign(), arrival.Runway.Designator)
+	if number := a.LandingNumber(arrival.PlaneId, arrival.Runway); number > 1 {
+		messageText = fmt.Sprintf("%s, number %s", messageText, SpokenNumber(number))
+	}
+	a.sayToArrival(arrival, fmt.Sprintf("%s, %s", messageText, weather.WindPhrase(a.Declination(arrival.Airfield))))
+}
+
+// runwayOccupied is whether anything is on the arrival's runway. Other planes on final are sequenced, not
+// in the way.
+func (a *AtcModel) runwayOccupied(arrival *Arrival) bool {
+	ignore := map[uint64]bool{arrival.PlaneId: true}
+	for planeId, state := range a.PlaneStates {
+		if state.State() == StateOnFinal {
+			ignore[planeId] = true
+		}
+	}
+	traffic, _ := a.RunwayTrafficAt(arrival.Airfield, arrival.Runway, ignore)
+	return traffic == TrafficOnRunway
+}
+
+func (a *AtcModel) sayToArrival(arrival *Arrival, text string) {
+	a.callArrival(arrival, text, message.PriorityClearance)
+}
+
+// warnArrival is for safety calls, which go out ahead of anything else waiting for the frequency
+func (a *AtcModel) warnArrival(arrival *Arrival, text string) {
+	a.callArrival(arrival, text, message.PrioritySafety)
+}
+
+func (a *AtcModel) callArrival(arrival *Arrival, text string, priority message.Priority) {
+	if a.messageOut == nil {
+		log.Warn().Msgf("no outgoing channel, dropping message to %d: %s", arrival.PlaneId, text)
+		return
+	}
+	a.messageOut <- message.OutgoingMessage{
+		Message:  message.FromMessage(arrival.Request.Context, &arrival.Request, text),
+		Priority: priority,
+		// each call to an arrival overtakes the last if it's still waiting, a landing clearance is no good
+		// once they've been sent around
+		Key: fmt.Sprintf("arrival-%d", arrival.PlaneId),
+	}
+}
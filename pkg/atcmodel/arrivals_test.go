@@ -0,0 +1,159 @@
+package atcmodel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/sim"
+	"github.com/dharmab/skyeye/pkg/trackfiles"
+	"github.com/martinlindhe/unit"
+	"github.com/paulmach/orb"
+	"github.com/stretchr/testify/assert"
+)
+
+func updatePlane(model *AtcModel, id uint64, at time.Time, point orb.Point, heading unit.Angle, agl unit.Length) {
+	frame := trackfiles.Frame{Time: at, Point: point, Heading: heading, AGL: ptrlength(agl)}
+	labels := trackfiles.Labels{ID: id}
+	if existing, ok := model.AllPlaneData[id]; ok {
+		labels = existing.Labels
+	}
+	model.AllPlaneData[id] = &sim.Updated{Labels: labels, Frame: frame}
+	model.PlaneState(id).UpdateFromTrack(frame)
+}
+
+func receiveAll(messages chan message.OutgoingMessage) []string {
+	texts := []string{}
+	for {
+		select {
+		case msg := <-messages:
+			texts = append(texts, msg.Message.Data)
+		default:
+			return texts
+		}
+	}
+}
+
+func TestChoosePatternEntry(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	runway04 := atcMap.AirfieldByName("Anapa").RunwayEndByDesignator("04")
+
+	assert.Equal(t, EntryStraightIn, ChoosePatternEntry(pointFrom(runway04.Threshold, 222*unit.Degree, 10*unit.NauticalMile), runway04))
+	assert.Equal(t, EntryStraightIn, ChoosePatternEntry(pointFrom(runway04.Threshold, 200*unit.Degree, 10*unit.NauticalMile), runway04))
+	assert.Equal(t, EntryOverhead, ChoosePatternEntry(pointFrom(runway04.Threshold, 42*unit.Degree, 10*unit.NauticalMile), runway04))
+	assert.Equal(t, EntryOverhead, ChoosePatternEntry(pointFrom(runway04.Threshold, 312*unit.Degree, 10*unit.NauticalMile), runway04))
+}
+
+func TestArrivalFlow(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	model := NewAtcModel(atcMap)
+	model.messageOut = make(chan message.OutgoingMessage, 10)
+
+	anapa := model.Map.AirfieldByName("Anapa")
+	runway04 := anapa.RunwayEndByDesignator("04")
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	updatePlane(model, 1, now, pointFrom(anapa.Position, 270*unit.Degree, 30*unit.NauticalMile), 90*unit.Degree, 5000*unit.Foot)
+	assert.Equal(t, StateEnroute, model.PlaneState(1).State())
+
+	model.AddArrival(&Arrival{
+		PlaneId:  1,
+		Airfield: anapa,
+		Runway:   runway04,
+		Entry:    EntryOverhead,
+		Request:  message.Message[string]{Context: context.Background(), ClientName: "hawg31"},
+	})
+	assert.Equal(t, StateInbound, model.PlaneState(1).State(), "calling inbound moves the plane to inbound")
+	assert.Empty(t, receiveAll(model.messageOut))
+
+	// someone else is already on a short final
+	now = now.Add(time.Minute)
+	updatePlane(model, 2, now, pointFrom(runway04.Threshold, 222*unit.Degree, 1*unit.NauticalMile), 42*unit.Degree, 300*unit.Foot)
+	assert.Equal(t, StateOnFinal, model.PlaneState(2).State())
+
+	now = now.Add(5 * time.Minute)
+	updatePlane(model, 1, now, pointFrom(anapa.Position, 132*unit.Degree, 2*unit.NauticalMile), 312*unit.Degree, 1500*unit.Foot)
+	assert.Equal(t, []string{"hawg31, report initial runway 04"}, receiveAll(model.messageOut))
+
+	now = now.Add(time.Minute)
+	updatePlane(model, 1, now, pointFrom(runway04.Threshold, 222*unit.Degree, 3*unit.NauticalMile), 42*unit.Degree, 900*unit.Foot)
+	assert.Equal(t, []string{"hawg31, runway 04, cleared to land, number two, wind calm"}, receiveAll(model.messageOut))
+	assert.True(t, model.Arrivals[1].ClearedToLand)
+
+	now = now.Add(time.Minute)
+	updatePlane(model, 1, now, pointFrom(runway04.Threshold, 42*unit.Degree, 300*unit.Meter), 42*unit.Degree, 0)
+	now = now.Add(20 * time.Second)
+	updatePlane(model, 1, now, pointFrom(pointFrom(runway04.Threshold, 42*unit.Degree, 900*unit.Meter), 312*unit.Degree, 100*unit.Meter), 312*unit.Degree, 0)
+	assert.Equal(t, StateVacated, model.PlaneState(1).State())
+	assert.Equal(t, []string{"hawg31, taxi to parking"}, receiveAll(model.messageOut))
+	assert.NotContains(t, model.Arrivals, uint64(1))
+}
+
+func TestArrivalRunwayOccupied(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	model := NewAtcModel(atcMap)
+	model.messageOut = make(chan message.OutgoingMessage, 10)
+
+	anapa := model.Map.AirfieldByName("Anapa")
+	runway04 := anapa.RunwayEndByDesignator("04")
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	// someone still rolling out from the last landing
+	updatePlane(model, 2, now, pointFrom(runway04.Threshold, 42*unit.Degree, 900*unit.Meter), 42*unit.Degree, 0)
+
+	updatePlane(model, 1, now, pointFrom(runway04.Threshold, 222*unit.Degree, 12*unit.NauticalMile), 42*unit.Degree, 3000*unit.Foot)
+	model.AddArrival(&Arrival{PlaneId: 1, Airfield: anapa, Runway: runway04, Entry: EntryStraightIn,
+		Request: message.Message[string]{Context: context.Background(), ClientName: "hawg31"}})
+
+	now = now.Add(2 * time.Minute)
+	updatePlane(model, 1, now, pointFrom(runway04.Threshold, 222*unit.Degree, 3*unit.NauticalMile), 42*unit.Degree, 900*unit.Foot)
+	assert.Equal(t, StateOnFinal, model.PlaneState(1).State())
+	assert.Equal(t, []string{"hawg31, runway 04, continue, traffic on the runway"}, receiveAll(model.messageOut))
+	assert.False(t, model.Arrivals[1].ClearedToLand)
+
+	model.ClearWaitingArrivals()
+	assert.Empty(t, receiveAll(model.messageOut), "still on the runway")
+
+	now = now.Add(20 * time.Second)
+	updatePlane(model, 2, now, pointFrom(pointFrom(runway04.Threshold, 42*unit.Degree, 900*unit.Meter), 312*unit.Degree, 100*unit.Meter), 312*unit.Degree, 0)
+	updatePlane(model, 1, now, pointFrom(runway04.Threshold, 222*unit.Degree, 2*unit.NauticalMile), 42*unit.Degree, 600*unit.Foot)
+	model.ClearWaitingArrivals()
+	assert.Equal(t, []string{"hawg31, runway 04, cleared to land, wind calm"}, receiveAll(model.messageOut))
+	assert.True(t, model.Arrivals[1].ClearedToLand)
+
+	model.ClearWaitingArrivals()
+	assert.Empty(t, receiveAll(model.messageOut), "cleared once")
+}
+
+func TestArrivalWrongRunway(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	model := NewAtcModel(atcMap)
+	model.messageOut = make(chan message.OutgoingMessage, 10)
+
+	anapa := model.Map.AirfieldByName("Anapa")
+	runway04 := anapa.RunwayEndByDesignator("04")
+	runway22 := anapa.RunwayEndByDesignator("22")
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	updatePlane(model, 1, now, pointFrom(anapa.Position, 42*unit.Degree, 12*unit.NauticalMile), 222*unit.Degree, 3000*unit.Foot)
+	model.AddArrival(&Arrival{PlaneId: 1, Airfield: anapa, Runway: runway04, Entry: EntryStraightIn,
+		Request: message.Message[string]{Context: context.Background(), ClientName: "hawg31"}})
+
+	now = now.Add(2 * time.Minute)
+	updatePlane(model, 1, now, pointFrom(runway22.Threshold, 42*unit.Degree, 3*unit.NauticalMile), 222*unit.Degree, 900*unit.Foot)
+	select {
+	case msg := <-model.messageOut:
+		assert.Equal(t, "hawg31, go around, runway 04 in use", msg.Message.Data)
+		assert.Equal(t, message.PrioritySafety, msg.Priority)
+		assert.Equal(t, "arrival-1", msg.Key)
+	default:
+		assert.Fail(t, "Expected a go around")
+	}
+	assert.Empty(t, receiveAll(model.messageOut))
+	assert.False(t, model.Arrivals[1].ClearedToLand)
+}
@@ -2,8 +2,12 @@ package atcmodel
 
 import (
 	"context"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/bearings"
 	"github.com/dharmab/skyeye/pkg/sim"
 	"github.com/dharmab/skyeye/pkg/simpleradio/types"
 	"github.com/martinlindhe/unit"
@@ -13,12 +17,73 @@ import (
 const IS_AIRBORN_AGL = 10 * unit.Meter
 
 type AtcModel struct {
-	Map          AtcMap
+	Map AtcMap
+	// mission time of the latest telemetry. DCS runs missions in the terrain's local time
+	GameTime     time.Time
 	Squads       map[types.Radio][]*AtcSquadron
 	PlaneToSquad map[uint64]*AtcSquadron
 
 	AllPlaneData map[uint64]*sim.Updated
 	CallsignToId map[string]*uint64
+	// SRS client name to the plane they're flying, once we've worked it out
+	Speakers map[string]uint64
+
+	// every tracked plane has a state, squads share the same instances
+	PlaneStates    map[uint64]PlaneState
+	stateListeners []StateListener
+
+	// sea level conditions from the mission, use WeatherAt for what to report at an airfield
+	Weather Weather
+	// reads the weather again when the mission changes, nil to keep what was set
+	LoadWeather func() (Weather, error)
+
+	// planes that have called inbound, by plane id
+	Arrivals map[uint64]*Arrival
+	// landing order for each runway with arrivals, first to land first
+	Sequences map[*RunwayEnd][]uint64
+	// what each airfield's ATIS is currently saying
+	AtisInformation map[*Airfield]*AtisInformation
+	// magnetic variation at each airfield on the mission date, worked out the first time it's needed
+	declinations map[*Airfield]unit.Angle
+	// one for each frequency we listen on, staffed before the model starts
+	Controllers []*Controller
+	// frequencies worked in FM, the rest are AM
+	FMFrequencies []unit.Frequency
+	// where unprompted controller calls go, set when the model starts
+	messageOut chan message.OutgoingMessage
+	// published from the model loop for readers on other goroutines
+	latestSnapshot atomic.Pointer[Snapshot]
+}
+
+func NewAtcModel(atcMap AtcMap) *AtcModel {
+	model := &AtcModel{
+		Map:             atcMap,
+		Squads:          make(map[types.Radio][]*AtcSquadron),
+		PlaneToSquad:    make(map[uint64]*AtcSquadron),
+		AllPlaneData:    make(map[uint64]*sim.Updated),
+		CallsignToId:    make(map[string]*uint64),
+		Speakers:        make(map[string]uint64),
+		PlaneStates:     make(map[uint64]PlaneState),
+		Weather:         StandardWeather(),
+		Arrivals:        make(map[uint64]*Arrival),
+		Sequences:       make(map[*RunwayEnd][]uint64),
+		AtisInformation: make(map[*Airfield]*AtisInformation),
+		declinations:    make(map[*Airfield]unit.Angle),
+	}
+	model.OnStateTransition(model.onArrivalTransition)
+	return model
+}
+
+// OnStateTransition registers a listener for every plane's state changes. Listeners are called from the
+// model loop, so they can safely read and modify the model.
+func (a *AtcModel) OnStateTransition(listener StateListener) {
+	a.stateListeners = append(a.stateListeners, listener)
+}
+
+func (a *AtcModel) publishTransition(transition StateTransition) {
+	for _, listener := range a.stateListeners {
+		listener(transition)
+	}
 }
 
 // needed to avoid circular dependencies with parsed commands
@@ -27,13 +92,29 @@ type AtcCommand interface {
 }
 
 func (a *AtcModel) Start(ctx context.Context, simStarted chan sim.Started, simUpdated chan sim.Updated, simFaded chan sim.Faded, commands chan AtcCommand,
-	messageOut chan message.OutgoingMessage) {
+	outbox message.Outbox) {
+	// everything the model says goes out through the controller on the frequency, in their voice
+	calls := make(chan message.OutgoingMessage, 10)
+	go a.dispatchCalls(ctx, calls, outbox)
+	a.messageOut = calls
+	atisTicker := time.NewTicker(ATIS_INTERVAL)
+	defer atisTicker.Stop()
+	snapshotTicker := time.NewTicker(SNAPSHOT_INTERVAL)
+	defer snapshotTicker.Stop()
+	a.publishSnapshot()
+
 	for {
 		select {
 		case <-ctx.Done():
 			log.Info().Msg("atc loop exiting")
 			return
 
+		case <-atisTicker.C:
+			a.broadcastAtis()
+
+		case <-snapshotTicker.C:
+			a.publishSnapshot()
+
 		case <-simStarted:
 			// new mission started
 			log.Info().Msg("atc model notified of mission change")
@@ -41,17 +122,21 @@ func (a *AtcModel) Start(ctx context.Context, simStarted chan sim.Started, simUp
 
 		case updated := <-simUpdated:
 			if squad, ok := a.PlaneToSquad[updated.Labels.ID]; ok {
-				if plane, okPlane := squad.PlaneStates[updated.Labels.ID]; okPlane {
-					plane.UpdateFromTrack(updated.Frame)
-				} else {
+				if _, okPlane := squad.PlaneStates[updated.Labels.ID]; !okPlane {
 					log.Warn().Msgf("could not find plane %d (%s) in squad", updated.Labels.ID, updated.Labels.Name)
 				}
 			}
+			if updated.Frame.Time.After(a.GameTime) {
+				a.GameTime = updated.Frame.Time
+			}
 			a.AllPlaneData[updated.Labels.ID] = &updated
+			a.PlaneState(updated.Labels.ID).UpdateFromTrack(updated.Frame)
 			if _, ok := a.CallsignToId[updated.Labels.Name]; !ok {
 				log.Info().Msgf("added callsign mapping %s -> %d", updated.Labels.Name, updated.Labels.ID)
 				a.CallsignToId[updated.Labels.Name] = &updated.Labels.ID
 			}
+			a.Resequence()
+			a.ClearWaitingArrivals()
 
 		case removed := <-simFaded:
 			if squad, ok := a.PlaneToSquad[removed.ID]; ok {
@@ -64,10 +149,14 @@ func (a *AtcModel) Start(ctx context.Context, simStarted chan sim.Started, simUp
 				delete(a.CallsignToId, planeData.Labels.Name)
 				delete(a.AllPlaneData, removed.ID)
 			}
+			delete(a.PlaneStates, removed.ID)
+			delete(a.Arrivals, removed.ID)
+			a.forgetSpeakers(removed.ID)
 
 		case cmd := <-commands:
 			log.Info().Msgf("atc executing command %s", cmd)
-			cmd.Execute(a, messageOut)
+			cmd.Execute(a, calls)
+			a.publishSnapshot()
 		}
 	}
 }
@@ -80,9 +169,100 @@ type SquadSearchResult struct {
 	AGL              unit.Length
 }
 
+// FindPlaneByName looks up a plane by the name telemetry reports for it, which for players is their
+// multiplayer name and usually matches their SRS client name
+func (a *AtcModel) FindPlaneByName(name string) (uint64, bool) {
+	if id, ok := a.CallsignToId[name]; ok {
+		return *id, true
+	}
+	for callsign, id := range a.CallsignToId {
+		if strings.EqualFold(callsign, name) {
+			return *id, true
+		}
+	}
+	return 0, false
+}
+
+// ZuluTime converts the mission time to UTC using the terrain's time zone
+func (a *AtcModel) ZuluTime() time.Time {
+	if a.GameTime.IsZero() {
+		return time.Time{}
+	}
+	local := time.Date(a.GameTime.Year(), a.GameTime.Month(), a.GameTime.Day(), a.GameTime.Hour(),
+		a.GameTime.Minute(), a.GameTime.Second(), a.GameTime.Nanosecond(), time.UTC)
+	return local.Add(-a.Map.UtcOffset)
+}
+
+// WeatherAt returns the conditions to report at the airfield
+func (a *AtcModel) WeatherAt(airfield *Airfield) Weather {
+	return a.Weather.AtElevation(airfield.Elevation)
+}
+
+// Declination is the magnetic variation at the airfield. Pilots are given magnetic headings and winds, like the
+// runway designators, while the map and the mission are true.
+func (a *AtcModel) Declination(airfield *Airfield) unit.Angle {
+	if declination, ok := a.declinations[airfield]; ok {
+		return declination
+	}
+	declination, err := bearings.Declination(airfield.Position, a.GameTime)
+	if err != nil {
+		log.Warn().Err(err).Msgf("no magnetic variation for %s, giving true bearings", airfield.Name)
+	}
+	a.declinations[airfield] = declination
+	return declination
+}
+
+// PlaneState returns the state for the plane, starting to track it if it's new
+func (a *AtcModel) PlaneState(planeId uint64) PlaneState {
+	state, ok := a.PlaneStates[planeId]
+	if !ok {
+		state = NewAircraftState(planeId, &a.Map, a.publishTransition)
+		a.PlaneStates[planeId] = state
+	}
+	return state
+}
+
 func (a *AtcModel) reset() {
 	log.Info().Msg("resetting atc model")
+	a.GameTime = time.Time{}
 	for r := range a.Squads {
 		delete(a.Squads, r)
 	}
+	for id := range a.PlaneToSquad {
+		delete(a.PlaneToSquad, id)
+	}
+	for id := range a.AllPlaneData {
+		delete(a.AllPlaneData, id)
+	}
+	for callsign := range a.CallsignToId {
+		delete(a.CallsignToId, callsign)
+	}
+	for id := range a.PlaneStates {
+		delete(a.PlaneStates, id)
+	}
+	for id := range a.Arrivals {
+		delete(a.Arrivals, id)
+	}
+	for runway := range a.Sequences {
+		delete(a.Sequences, runway)
+	}
+	for clientName := range a.Speakers {
+		delete(a.Speakers, clientName)
+	}
+	// the letters start again from alpha
+	for airfield := range a.AtisInformation {
+		delete(a.AtisInformation, airfield)
+	}
+	// the next mission may be years apart
+	for airfield := range a.declinations {
+		delete(a.declinations, airfield)
+	}
+	if a.LoadWeather != nil {
+		weather, err := a.LoadWeather()
+		if err != nil {
+			log.Error().Err(err).Msg("failed to reload mission weather, keeping the last")
+			return
+		}
+		a.SetWeather(weather)
+	}
 }
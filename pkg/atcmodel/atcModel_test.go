@@ -0,0 +1,70 @@
+package atcmodel
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/bearings"
+	"github.com/martinlindhe/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReset(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	model := NewAtcModel(atcMap)
+
+	anapa := model.Map.AirfieldByName("Anapa")
+	runway04 := anapa.RunwayEndByDesignator("04")
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	updatePlane(model, 1, now, pointFrom(runway04.Threshold, 222*unit.Degree, 8*unit.NauticalMile), 42*unit.Degree, 3000*unit.Foot)
+	model.AllPlaneData[1].Labels.Name = "hawg31"
+	model.CallsignToId["hawg31"] = &model.AllPlaneData[1].Labels.ID
+	model.PlaneToSquad[1] = &AtcSquadron{}
+	model.Speakers["hawg31"] = 1
+	model.AddArrival(&Arrival{PlaneId: 1, Airfield: anapa, Runway: runway04, Entry: EntryStraightIn,
+		Request: message.Message[string]{Context: context.Background(), ClientName: "hawg31"}})
+	model.Resequence()
+	model.Atis(anapa)
+
+	model.reset()
+	assert.Empty(t, model.AllPlaneData)
+	assert.Empty(t, model.CallsignToId)
+	assert.Empty(t, model.PlaneToSquad)
+	assert.Empty(t, model.PlaneStates)
+	assert.Empty(t, model.Speakers)
+	assert.Empty(t, model.Arrivals)
+	assert.Empty(t, model.Sequences)
+	assert.Empty(t, model.AtisInformation)
+	_, ok := model.FindPlaneByName("hawg31")
+	assert.False(t, ok, "the old mission's planes are forgotten")
+}
+
+func TestReset_ReloadsWeather(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	model := NewAtcModel(atcMap)
+
+	// the mission file was swapped for a windy one
+	windy := StandardWeather()
+	windy.WindSpeed = 15 * unit.Knot
+	windy.WindDirection = bearings.NewTrueBearing(220 * unit.Degree)
+	model.LoadWeather = func() (Weather, error) {
+		return windy, nil
+	}
+	model.reset()
+	assert.Equal(t, windy, model.Weather)
+	anapa := model.Map.AirfieldByName("Anapa")
+	assert.Equal(t, "22", anapa.ActiveRunway().Designator)
+
+	// a bad file keeps the weather we had
+	model.LoadWeather = func() (Weather, error) {
+		return Weather{}, fmt.Errorf("no mission")
+	}
+	model.reset()
+	assert.Equal(t, windy, model.Weather)
+}
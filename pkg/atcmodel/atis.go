@@ -0,0 +1,107 @@
+package atcmodel
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/simpleradio/voice"
+	"github.com/rs/zerolog/log"
+)
+
+// how often every ATIS is read out
+const ATIS_INTERVAL = 2 * time.Minute
+
+var phoneticAlphabet = []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel", "india",
+	"juliett", "kilo", "lima", "mike", "november", "oscar", "papa", "quebec", "romeo", "sierra", "tango", "uniform",
+	"victor", "whiskey", "x-ray", "yankee", "zulu"}
+
+// AtisInformation is the current broadcast for one airfield. The letter moves on whenever the conditions change.
+type AtisInformation struct {
+	Airfield   *Airfield
+	letter     int
+	conditions string
+}
+
+func (i *AtisInformation) Letter() string {
+	return phoneticAlphabet[i.letter%len(phoneticAlphabet)]
+}
+
+// Atis returns the current ATIS for the airfield, bringing it up to date with the weather and active runway
+func (a *AtcModel) Atis(airfield *Airfield) *AtisInformation {
+	info, ok := a.AtisInformation[airfield]
+	if !ok {
+		info = &AtisInformation{Airfield: airfield}
+		a.AtisInformation[airfield] = info
+	}
+
+	conditions := a.atisConditions(airfield)
+	if info.conditions != "" && info.conditions != conditions {
+		info.letter++
+		log.Info().Msgf("%s ATIS now information %s", airfield.Name, info.Letter())
+	}
+	info.conditions = conditions
+	return info
+}
+
+// AtisReport is the full text read out on the ATIS frequency
+func (a *AtcModel) AtisReport(airfield *Airfield) string {
+	info := a.Atis(airfield)
+	return fmt.Sprintf("%s information %s. %s. advise on initial contact you have information %s",
+		strings.ToLower(airfield.Name), info.Letter(), info.conditions, info.Letter())
+}
+
+func (a *AtcModel) atisConditions(airfield *Airfield) string {
+	weather := a.WeatherAt(airfield)
+	parts := []string{}
+	if runway := airfield.ActiveRunway(); runway != nil {
+		parts = append(parts, fmt.Sprintf("runway %s in use", runway.Designator))
+	}
+	parts = append(parts,
+		weather.WindPhrase(a.Declination(airfield)),
+		weather.VisibilityPhrase(),
+		weather.CloudsPhrase(airfield.Elevation),
+		weather.TemperaturePhrase(),
+		weather.AltimeterPhrase(),
+	)
+	return strings.Join(parts, ", ")
+}
+
+// broadcastAtis reads out the ATIS for every airfield with an ATIS controller staffed, on that controller's
+// frequencies
+func (a *AtcModel) broadcastAtis() {
+	if a.messageOut == nil {
+		return
+	}
+
+	for _, controller := range a.Controllers {
+		if controller.Facility != FacilityATIS || controller.Airfield == nil {
+			continue
+		}
+		airfield := controller.Airfield
+
+		frequencies := []voice.Frequency{}
+		for _, frequency := range controller.Frequencies {
+			frequencies = append(frequencies, voice.Frequency{
+				Frequency:  frequency.Hertz(),
+				Modulation: byte(a.Modulation(frequency)),
+			})
+		}
+
+		a.messageOut <- message.OutgoingMessage{
+			Message: message.Message[string]{
+				Context:     context.Background(),
+				TraceId:     fmt.Sprintf("atis-%s", strings.ToLower(airfield.Name)),
+				ClientName:  fmt.Sprintf("%s ATIS", airfield.Name),
+				Frequencies: frequencies,
+				Data:        a.AtisReport(airfield),
+			},
+			Priority: message.PriorityInformational,
+			// a newer report replaces one still waiting, and there's no point reading one out once it's due again
+			Key:     fmt.Sprintf("atis-%s", strings.ToLower(airfield.Name)),
+			Expires: time.Now().Add(ATIS_INTERVAL),
+		}
+	}
+}
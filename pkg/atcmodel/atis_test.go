@@ -0,0 +1,71 @@
+package atcmodel
+
+import (
+	"testing"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/bearings"
+	"github.com/dharmab/skyeye/pkg/simpleradio/types"
+	"github.com/martinlindhe/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtisReport(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	model := NewAtcModel(atcMap)
+	anapa := model.Map.AirfieldByName("Anapa")
+
+	assert.Equal(t, "anapa information alpha. runway 04 in use, wind calm, visibility 10 kilometers or more, sky clear, "+
+		"temperature 15, altimeter 2992. advise on initial contact you have information alpha", model.AtisReport(anapa))
+	assert.Equal(t, "alpha", model.Atis(anapa).Letter(), "nothing changed")
+
+	model.Weather = Weather{
+		WindDirection: bearings.NewTrueBearing(220 * unit.Degree),
+		WindSpeed:     12 * unit.Knot,
+		QNH:           unit.Pressure(29.74) * unit.InchOfMercury,
+		Temperature:   unit.FromCelsius(-3),
+		Visibility:    4500 * unit.Meter,
+		CloudCover:    CloudsBroken,
+		CloudBase:     anapa.Elevation + 2500*unit.Foot,
+	}
+	// the wind is magnetic, anapa's variation is about 8 degrees east
+	assert.Equal(t, "anapa information bravo. runway 04 in use, wind 212 at 12, visibility 4 kilometers, broken 2500, "+
+		"temperature minus 3, altimeter 2974. advise on initial contact you have information bravo", model.AtisReport(anapa))
+
+	anapa.SetActiveRunway(anapa.RunwayEndByDesignator("22"))
+	assert.Equal(t, "charlie", model.Atis(anapa).Letter(), "runway change moves the letter on")
+
+	// each airfield has its own letter
+	assert.Equal(t, "alpha", model.Atis(model.Map.AirfieldByName("Batumi")).Letter())
+}
+
+func TestBroadcastAtis(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	missionFrequencies(&atcMap)
+	model := NewAtcModel(atcMap)
+	model.messageOut = make(chan message.OutgoingMessage, 10)
+	anapaAtis := model.Map.AirfieldByName("Anapa").FacilityFrequencies(FacilityATIS)
+	anapaTower := model.Map.AirfieldByName("Anapa").FacilityFrequencies(FacilityTower)
+	model.StaffControllers(append(append(anapaAtis, anapaTower...), 305*unit.Megahertz), nil)
+	model.FMFrequencies = []unit.Frequency{121.15 * unit.Megahertz}
+
+	model.broadcastAtis()
+
+	broadcasts := map[string]message.Message[string]{}
+	for len(model.messageOut) > 0 {
+		msg := <-model.messageOut
+		broadcasts[msg.Message.ClientName] = msg.Message
+	}
+	assert.Len(t, broadcasts, 1, "only airfields with an ATIS controller staffed")
+
+	anapa, ok := broadcasts["Anapa ATIS"]
+	assert.True(t, ok)
+	assert.Len(t, anapa.Frequencies, 2)
+	assert.InDelta(t, 250200000.0, anapa.Frequencies[0].Frequency, 1)
+	assert.InDelta(t, 121150000.0, anapa.Frequencies[1].Frequency, 1)
+	assert.Equal(t, byte(types.ModulationAM), anapa.Frequencies[0].Modulation)
+	assert.Equal(t, byte(types.ModulationFM), anapa.Frequencies[1].Modulation)
+	assert.Contains(t, anapa.Data, "anapa information alpha")
+}
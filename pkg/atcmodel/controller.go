@@ -0,0 +1,149 @@
+package atcmodel
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/simpleradio/types"
+	"github.com/dharmab/skyeye/pkg/simpleradio/voice"
+	"github.com/martinlindhe/unit"
+	"github.com/rs/zerolog/log"
+)
+
+// Controller is the person working one facility at an airfield, on every frequency that facility has. Each has
+// their own voice, and remembers what they last told each pilot.
+type Controller struct {
+	// nil for a frequency that isn't an airfield's, where the controller answers anything
+	Airfield    *Airfield
+	Facility    Facility
+	Frequencies []unit.Frequency
+	// text to speech model, empty for the synthesizer's default
+	Voice string
+
+	mu sync.Mutex
+	// the last call to each SRS client, in case they ask us to say again
+	lastSaid map[string]message.OutgoingMessage
+}
+
+func NewController(airfield *Airfield, facility Facility, frequencies []unit.Frequency, voice string) *Controller {
+	return &Controller{
+		Airfield:    airfield,
+		Facility:    facility,
+		Frequencies: frequencies,
+		Voice:       voice,
+		lastSaid:    make(map[string]message.OutgoingMessage),
+	}
+}
+
+// Name is how the controller identifies themselves, e.g. "anapa ground"
+func (c *Controller) Name() string {
+	if c.Airfield == nil {
+		return "atc"
+	}
+	return fmt.Sprintf("%s %s", strings.ToLower(c.Airfield.Name), c.Facility)
+}
+
+// OnFrequency is true if the controller is working any of the frequencies
+func (c *Controller) OnFrequency(frequencies []voice.Frequency) bool {
+	for _, frequency := range frequencies {
+		for _, candidate := range c.Frequencies {
+			if math.Abs(float64(candidate)-frequency.Frequency) <= FREQUENCY_TOLERANCE.Hertz() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Say puts the call in the controller's voice, and remembers it in case the pilot asks us to say again
+func (c *Controller) Say(msg message.OutgoingMessage) message.OutgoingMessage {
+	msg.Model = c.Voice
+	c.mu.Lock()
+	c.lastSaid[msg.Message.ClientName] = msg
+	c.mu.Unlock()
+	return msg
+}
+
+// LastSaidTo is the last call the controller made to the SRS client
+func (c *Controller) LastSaidTo(clientName string) (message.OutgoingMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	msg, ok := c.lastSaid[clientName]
+	return msg, ok
+}
+
+// StaffControllers puts a controller on each frequency we're listening to. An airfield facility's frequencies
+// share one controller, and frequencies that aren't an airfield's get one each. The voices are handed out in
+// turn, so ground and tower sound like different people; without any every controller gets the synthesizer's
+// default voice.
+func (a *AtcModel) StaffControllers(frequencies []unit.Frequency, voices []string) {
+	if len(voices) == 0 {
+		voices = []string{""}
+	}
+	a.Controllers = nil
+	for _, frequency := range frequencies {
+		airfield, facility, ok := a.Map.StationOn(frequency)
+		if !ok {
+			airfield, facility = nil, FacilityUnknown
+		}
+		if existing := a.ControllerFor(airfield, facility); ok && existing != nil {
+			existing.Frequencies = append(existing.Frequencies, frequency)
+			continue
+		}
+
+		controller := NewController(airfield, facility, []unit.Frequency{frequency}, voices[len(a.Controllers)%len(voices)])
+		a.Controllers = append(a.Controllers, controller)
+		log.Info().Msgf("%s on %s with voice %s", controller.Name(), FrequencyPhrase(frequency), controller.Voice)
+	}
+}
+
+// Modulation is how we work the frequency
+func (a *AtcModel) Modulation(frequency unit.Frequency) types.Modulation {
+	for _, candidate := range a.FMFrequencies {
+		if math.Abs(float64(candidate-frequency)) <= float64(FREQUENCY_TOLERANCE) {
+			return types.ModulationFM
+		}
+	}
+	return types.ModulationAM
+}
+
+// ControllerFor is the controller staffing the facility, nil if there isn't one
+func (a *AtcModel) ControllerFor(airfield *Airfield, facility Facility) *Controller {
+	for _, controller := range a.Controllers {
+		if controller.Airfield == airfield && controller.Facility == facility {
+			return controller
+		}
+	}
+	return nil
+}
+
+// ControllerOn is the controller working any of the frequencies, nil if there isn't one. Controllers don't
+// change once staffed, so this is safe to call from any goroutine.
+func (a *AtcModel) ControllerOn(frequencies []voice.Frequency) *Controller {
+	for _, controller := range a.Controllers {
+		if controller.OnFrequency(frequencies) {
+			return controller
+		}
+	}
+	return nil
+}
+
+// dispatchCalls has the controller on the frequency make every call the model makes. The outbox never blocks,
+// so neither does the model.
+func (a *AtcModel) dispatchCalls(ctx context.Context, calls chan message.OutgoingMessage, outbox message.Outbox) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-calls:
+			if controller := a.ControllerOn(msg.Message.Frequencies); controller != nil {
+				msg = controller.Say(msg)
+			}
+			outbox.Send(msg)
+		}
+	}
+}
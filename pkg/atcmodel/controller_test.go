@@ -0,0 +1,89 @@
+package atcmodel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/sim"
+	"github.com/dharmab/skyeye/pkg/simpleradio/voice"
+	"github.com/martinlindhe/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+// replyCommand answers on the frequency it was given, like a player command does
+type replyCommand struct {
+	frequency unit.Frequency
+	text      string
+}
+
+func (c *replyCommand) Execute(atc *AtcModel, messageOut chan message.OutgoingMessage) error {
+	messageOut <- message.OutgoingMessage{
+		Message: message.Message[string]{
+			Context:     context.Background(),
+			ClientName:  "player",
+			Frequencies: []voice.Frequency{{Frequency: c.frequency.Hertz()}},
+			Data:        c.text,
+		},
+	}
+	return nil
+}
+
+// channelOutbox hands the model's calls to the test
+type channelOutbox chan message.OutgoingMessage
+
+func (o channelOutbox) Send(msg message.OutgoingMessage) {
+	o <- msg
+}
+
+func TestStaffControllers(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	missionFrequencies(&atcMap)
+	model := NewAtcModel(atcMap)
+	model.StaffControllers([]unit.Frequency{250 * unit.Megahertz, 121 * unit.Megahertz, 250.1 * unit.Megahertz,
+		305 * unit.Megahertz}, []string{"tower-voice", "ground-voice", "other-voice"})
+
+	assert.Len(t, model.Controllers, 3)
+	tower, ground, other := model.Controllers[0], model.Controllers[1], model.Controllers[2]
+	assert.Equal(t, "anapa tower", tower.Name())
+	assert.Len(t, tower.Frequencies, 2, "UHF and VHF tower are the same person")
+	assert.Equal(t, "tower-voice", tower.Voice)
+	assert.Equal(t, "anapa ground", ground.Name())
+	assert.Equal(t, "ground-voice", ground.Voice)
+	assert.Equal(t, "atc", other.Name())
+	assert.Nil(t, other.Airfield)
+
+	assert.Equal(t, tower, model.ControllerOn([]voice.Frequency{{Frequency: (121 * unit.Megahertz).Hertz()}}))
+	assert.Nil(t, model.ControllerOn([]voice.Frequency{{Frequency: (251 * unit.Megahertz).Hertz()}}))
+}
+
+func TestControllersSpeakInTheirOwnVoice(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	missionFrequencies(&atcMap)
+	model := NewAtcModel(atcMap)
+	model.StaffControllers([]unit.Frequency{250 * unit.Megahertz, 250.1 * unit.Megahertz}, []string{"tower-voice", "ground-voice"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	commands := make(chan AtcCommand, 2)
+	messageOut := make(channelOutbox, 2)
+	go model.Start(ctx, make(chan sim.Started), make(chan sim.Updated), make(chan sim.Faded), commands, messageOut)
+
+	commands <- &replyCommand{frequency: 250.1 * unit.Megahertz, text: "hawg 3 1, taxi to runway 04"}
+	select {
+	case msg := <-messageOut:
+		assert.Equal(t, "ground-voice", msg.Model)
+		assert.Equal(t, "hawg 3 1, taxi to runway 04", msg.Message.Data)
+	case <-time.After(time.Second):
+		assert.Fail(t, "no reply")
+	}
+
+	last, ok := model.Controllers[1].LastSaidTo("player")
+	assert.True(t, ok)
+	assert.Equal(t, "hawg 3 1, taxi to runway 04", last.Message.Data)
+	_, ok = model.Controllers[0].LastSaidTo("player")
+	assert.False(t, ok, "tower didn't say anything")
+}
@@ -0,0 +1,160 @@
+package atcmodel
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/martinlindhe/unit"
+)
+
+// Facility is one of the controllers at an airfield. Small airfields don't staff every facility, so
+// HandledBy works out who does the job instead, e.g. tower doubles as ground when there's no ground frequency.
+type Facility int
+
+const (
+	FacilityUnknown Facility = iota
+	FacilityGround
+	FacilityTower
+	FacilityApproach
+	FacilityDeparture
+	FacilityATIS
+)
+
+// frequencies within this of each other are the same channel
+const FREQUENCY_TOLERANCE = 5 * unit.Kilohertz
+
+// UHF starts here, pilots are told the frequency on the same band they called on
+const UHF_BAND_START = 225 * unit.Megahertz
+
+func (f Facility) String() string {
+	switch f {
+	case FacilityGround:
+		return "ground"
+	case FacilityTower:
+		return "tower"
+	case FacilityApproach:
+		return "approach"
+	case FacilityDeparture:
+		return "departure"
+	case FacilityATIS:
+		return "atis"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseFacility reads the facility a pilot called, e.g. "tower", or FacilityUnknown
+func ParseFacility(spoken string) Facility {
+	switch spoken {
+	case "ground", "clearance", "delivery":
+		return FacilityGround
+	case "tower":
+		return FacilityTower
+	case "approach", "radar", "center", "control":
+		return FacilityApproach
+	case "departure":
+		return FacilityDeparture
+	case "atis":
+		return FacilityATIS
+	default:
+		return FacilityUnknown
+	}
+}
+
+// FacilityFrequencies are the airfield's own frequencies for the facility, empty if it isn't staffed
+func (a *Airfield) FacilityFrequencies(facility Facility) []unit.Frequency {
+	switch facility {
+	case FacilityGround:
+		return a.Frequencies.Ground
+	case FacilityTower:
+		return a.Frequencies.Tower
+	case FacilityApproach:
+		return a.Frequencies.Approach
+	case FacilityDeparture:
+		return a.Frequencies.Departure
+	case FacilityATIS:
+		return a.Frequencies.ATIS
+	default:
+		return nil
+	}
+}
+
+// SetFacilityFrequencies staffs the facility on the frequencies, e.g. when a server uses its own frequency plan
+func (a *Airfield) SetFacilityFrequencies(facility Facility, frequencies []unit.Frequency) {
+	switch facility {
+	case FacilityGround:
+		a.Frequencies.Ground = frequencies
+	case FacilityTower:
+		a.Frequencies.Tower = frequencies
+	case FacilityApproach:
+		a.Frequencies.Approach = frequencies
+	case FacilityDeparture:
+		a.Frequencies.Departure = frequencies
+	case FacilityATIS:
+		a.Frequencies.ATIS = frequencies
+	}
+}
+
+// StandIns are the facilities that do this one's job where it isn't staffed, in the order we try them
+func (f Facility) StandIns() []Facility {
+	switch f {
+	case FacilityDeparture:
+		return []Facility{FacilityApproach, FacilityTower}
+	case FacilityGround, FacilityApproach:
+		return []Facility{FacilityTower}
+	default:
+		return nil
+	}
+}
+
+// HandledBy is the facility that does the job at this airfield, FacilityUnknown if nobody does
+func (a *Airfield) HandledBy(facility Facility) Facility {
+	for _, candidate := range append([]Facility{facility}, facility.StandIns()...) {
+		if len(a.FacilityFrequencies(candidate)) > 0 {
+			return candidate
+		}
+	}
+	return FacilityUnknown
+}
+
+// FacilityOn is the facility working the frequency at this airfield
+func (a *Airfield) FacilityOn(frequency unit.Frequency) (Facility, bool) {
+	for _, facility := range []Facility{FacilityGround, FacilityTower, FacilityApproach, FacilityDeparture, FacilityATIS} {
+		for _, candidate := range a.FacilityFrequencies(facility) {
+			if math.Abs(float64(candidate-frequency)) <= float64(FREQUENCY_TOLERANCE) {
+				return facility, true
+			}
+		}
+	}
+	return FacilityUnknown, false
+}
+
+// StationOn finds the airfield and facility working a frequency
+func (m *AtcMap) StationOn(frequency unit.Frequency) (*Airfield, Facility, bool) {
+	for _, airfield := range m.Airfields {
+		if facility, ok := airfield.FacilityOn(frequency); ok {
+			return airfield, facility, true
+		}
+	}
+	return nil, FacilityUnknown, false
+}
+
+// ContactFrequency is the facility's frequency to give a pilot who called on the other one, on the same band
+// if there is one
+func (a *Airfield) ContactFrequency(facility Facility, calledOn unit.Frequency) (unit.Frequency, bool) {
+	frequencies := a.FacilityFrequencies(facility)
+	if len(frequencies) == 0 {
+		return 0, false
+	}
+	for _, frequency := range frequencies {
+		if (frequency >= UHF_BAND_START) == (calledOn >= UHF_BAND_START) {
+			return frequency, true
+		}
+	}
+	return frequencies[0], true
+}
+
+// FrequencyPhrase is how a controller reads out a frequency, e.g. "250.1"
+func FrequencyPhrase(frequency unit.Frequency) string {
+	return strconv.FormatFloat(math.Round(frequency.Megahertz()*1000)/1000, 'f', -1, 64)
+}
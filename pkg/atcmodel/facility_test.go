@@ -0,0 +1,66 @@
+package atcmodel
+
+import (
+	"testing"
+
+	"github.com/martinlindhe/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+// missionFrequencies sets up Anapa and Batumi like a mission that staffs them. DCS only publishes tower
+// frequencies, so everything else comes from the config.
+func missionFrequencies(atcMap *AtcMap) {
+	anapa := atcMap.AirfieldByName("Anapa")
+	anapa.SetFacilityFrequencies(FacilityGround, []unit.Frequency{250.1 * unit.Megahertz, 121.05 * unit.Megahertz})
+	anapa.SetFacilityFrequencies(FacilityATIS, []unit.Frequency{250.2 * unit.Megahertz, 121.15 * unit.Megahertz})
+	batumi := atcMap.AirfieldByName("Batumi")
+	batumi.SetFacilityFrequencies(FacilityGround, []unit.Frequency{260.1 * unit.Megahertz, 131.05 * unit.Megahertz})
+	batumi.SetFacilityFrequencies(FacilityApproach, []unit.Frequency{260.3 * unit.Megahertz, 131.25 * unit.Megahertz})
+}
+
+func TestHandledBy(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	missionFrequencies(&atcMap)
+	batumi := atcMap.AirfieldByName("Batumi")
+	krymsk := atcMap.AirfieldByName("Krymsk")
+
+	assert.Equal(t, FacilityGround, batumi.HandledBy(FacilityGround))
+	assert.Equal(t, FacilityApproach, batumi.HandledBy(FacilityDeparture))
+	assert.Equal(t, FacilityTower, krymsk.HandledBy(FacilityGround), "tower doubles as ground")
+	assert.Equal(t, FacilityTower, krymsk.HandledBy(FacilityDeparture))
+	assert.Equal(t, FacilityUnknown, krymsk.HandledBy(FacilityATIS))
+}
+
+func TestStationOn(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	missionFrequencies(&atcMap)
+
+	airfield, facility, ok := atcMap.StationOn(250.1 * unit.Megahertz)
+	assert.True(t, ok)
+	assert.Equal(t, "Anapa", airfield.Name)
+	assert.Equal(t, FacilityGround, facility)
+
+	airfield, facility, ok = atcMap.StationOn(131.25 * unit.Megahertz)
+	assert.True(t, ok)
+	assert.Equal(t, "Batumi", airfield.Name)
+	assert.Equal(t, FacilityApproach, facility)
+
+	_, _, ok = atcMap.StationOn(305 * unit.Megahertz)
+	assert.False(t, ok)
+
+	anapa := atcMap.AirfieldByName("Anapa")
+	frequency, ok := anapa.ContactFrequency(FacilityGround, 121*unit.Megahertz)
+	assert.True(t, ok)
+	assert.Equal(t, "121.05", FrequencyPhrase(frequency), "same band as the call")
+	frequency, _ = anapa.ContactFrequency(FacilityGround, 250*unit.Megahertz)
+	assert.Equal(t, "250.1", FrequencyPhrase(frequency))
+}
+
+func TestParseFacility(t *testing.T) {
+	assert.Equal(t, FacilityTower, ParseFacility("tower"))
+	assert.Equal(t, FacilityGround, ParseFacility("clearance"))
+	assert.Equal(t, FacilityApproach, ParseFacility("radar"))
+	assert.Equal(t, FacilityUnknown, ParseFacility(""))
+}
@@ -3,8 +3,10 @@ package atcmodel
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/dharmab/skyeye/pkg/sim"
+	"github.com/dharmab/skyeye/pkg/simpleradio/types"
 	"github.com/dharmab/skyeye/pkg/spatial"
 	"github.com/martinlindhe/unit"
 	"github.com/rs/zerolog/log"
@@ -20,6 +22,9 @@ func (a *AtcModel) FindCandidatesForSquad(ctx context.Context, leaderId uint64,
 		return nil, fmt.Errorf("leader with id %d does not exist", leaderId)
 	}
 
+	// the flight is close enough to the leader to share their airfield's elevation
+	airfield, _ := a.Map.NearestAirfield(leaderData.Frame.Point)
+
 	planeTypes := make(map[string]struct{})
 	for _, str := range squadmatePlanes {
 		planeTypes[str] = struct{}{}
@@ -51,13 +56,42 @@ func (a *AtcModel) FindCandidatesForSquad(ctx context.Context, leaderId uint64,
 			PlaneType:        planeData.Labels.ACMIName,
 			Distance:         int(distance),
 			IsAlreadyInSquad: isAlreadyInSquad,
-			AGL:              *planeData.Frame.AGL,
+			AGL:              aboveGroundLevel(planeData.Frame, airfield),
 		})
 	}
 
+	// closest first, map iteration order is random
+	sort.Slice(candidatePlanes, func(i, j int) bool {
+		if candidatePlanes[i].Distance != candidatePlanes[j].Distance {
+			return candidatePlanes[i].Distance < candidatePlanes[j].Distance
+		}
+		return candidatePlanes[i].PlaneId < candidatePlanes[j].PlaneId
+	})
+
 	return candidatePlanes, nil
 }
 
+// CreateSquad groups the leader and wingmen into a squad talking on the radio, taking them out of any
+// squad they were in before
+func (a *AtcModel) CreateSquad(leaderId uint64, wingmen []uint64, radio types.Radio) *AtcSquadron {
+	squad := &AtcSquadron{
+		LeaderId:    leaderId,
+		PlaneStates: make(map[uint64]PlaneState),
+	}
+
+	for _, planeId := range append([]uint64{leaderId}, wingmen...) {
+		if oldSquad, ok := a.PlaneToSquad[planeId]; ok {
+			log.Info().Msgf("moving plane %d out of squad led by %d", planeId, oldSquad.LeaderId)
+			delete(oldSquad.PlaneStates, planeId)
+		}
+		squad.PlaneStates[planeId] = a.PlaneState(planeId)
+		a.PlaneToSquad[planeId] = squad
+	}
+
+	a.Squads[radio] = append(a.Squads[radio], squad)
+	return squad
+}
+
 func (a *AtcModel) DoesExistingSquadMatchTypes(leader uint64, planeTypes *[]string) bool {
 	squad, hasSquad := a.PlaneToSquad[leader]
 	if !hasSquad {
@@ -218,6 +218,53 @@ func TestFindCandidatesForSquad(t *testing.T) {
 			wantErr:       false,
 			errorContains: "",
 		},
+		{
+			name: "telemetry without AGL",
+			setupModel: func() *AtcModel {
+				model := &AtcModel{
+					AllPlaneData: make(map[uint64]*sim.Updated),
+					PlaneToSquad: make(map[uint64]*AtcSquadron),
+				}
+
+				// Leader F-16
+				model.AllPlaneData[1] = &sim.Updated{
+					Labels: trackfiles.Labels{
+						ID:       1,
+						ACMIName: "F-16C_50",
+					},
+					Frame: trackfiles.Frame{
+						Point: orb.Point{45, 45},
+					},
+				}
+
+				// Nearby F-16, no AGL so its altitude is used
+				model.AllPlaneData[2] = &sim.Updated{
+					Labels: trackfiles.Labels{
+						ID:       2,
+						ACMIName: "F-16C_50",
+					},
+					Frame: trackfiles.Frame{
+						Point:    spatial.PointAtBearingAndDistance(orb.Point{45, 45}, bearings.NewTrueBearing(10), unit.Meter*1000),
+						Altitude: 5 * unit.Meter,
+					},
+				}
+
+				return model
+			},
+			leaderId:    1,
+			planeTypes:  []string{"F-16C_50"},
+			maxDistance: 5000 * unit.Meter,
+			wantResults: []SquadSearchResult{
+				{
+					PlaneId:          2,
+					PlaneType:        "F-16C_50",
+					Distance:         1000,
+					IsAlreadyInSquad: false,
+					AGL:              5 * unit.Meter,
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "leader not found",
 			setupModel: func() *AtcModel {
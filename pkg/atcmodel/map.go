@@ -1,4 +1,333 @@
 package atcmodel
 
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dharmab/skyeye/pkg/bearings"
+	"github.com/dharmab/skyeye/pkg/spatial"
+	"github.com/martinlindhe/unit"
+	"github.com/paulmach/orb"
+)
+
+//go:embed terrains/*.json
+var terrainFiles embed.FS
+
+type RunwayEnd struct {
+	// spoken runway number, e.g. "22" or "13L"
+	Designator string
+	// true heading when taking off or landing towards this end
+	Heading   bearings.Bearing
+	Threshold orb.Point
+}
+
+type Runway struct {
+	Ends   [2]RunwayEnd
+	Length unit.Length
+}
+
+type AirfieldFrequencies struct {
+	Tower     []unit.Frequency
+	Ground    []unit.Frequency
+	Approach  []unit.Frequency
+	Departure []unit.Frequency
+	ATIS      []unit.Frequency
+}
+
+type Airfield struct {
+	Name string
+	// other names pilots use on the radio, e.g. "Lochini" for Tbilisi
+	Aliases     []string
+	Position    orb.Point
+	Elevation   unit.Length
+	Runways     []Runway
+	Frequencies AirfieldFrequencies
+	// nil if there's no taxiway data for this airfield
+	Taxiways *TaxiNetwork
+
+	activeRunway *RunwayEnd
+}
+
 type AtcMap struct {
+	Terrain string
+	// local mission time is this far ahead of zulu
+	UtcOffset time.Duration
+	Airfields []*Airfield
+}
+
+type latLon struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+type runwayData struct {
+	// low numbered end first, e.g. ["04", "22"]
+	Ends []string `json:"ends"`
+	// true heading of the first end
+	Heading float64 `json:"heading"`
+	Length  float64 `json:"length_m"`
+	// defaults to the airfield position
+	Center *latLon `json:"center"`
+	// how far the centerline is to the right of the center, looking along the first end's heading. Parallel
+	// runways sit either side of the airfield position, e.g. -192 for 12L and 192 for 12R at Dubai
+	Offset float64 `json:"offset_m"`
+}
+
+type airfieldData struct {
+	Name        string       `json:"name"`
+	Aliases     []string     `json:"aliases"`
+	Position    latLon       `json:"position"`
+	Elevation   float64      `json:"elevation_ft"`
+	Runways     []runwayData `json:"runways"`
+	Frequencies struct {
+		Tower     []float64 `json:"tower"`
+		Ground    []float64 `json:"ground"`
+		Approach  []float64 `json:"approach"`
+		Departure []float64 `json:"departure"`
+		ATIS      []float64 `json:"atis"`
+	} `json:"frequencies_mhz"`
+	Taxiways *taxiNetworkData `json:"taxiways"`
+}
+
+type terrainData struct {
+	Terrain        string         `json:"terrain"`
+	UtcOffsetHours float64        `json:"utc_offset_hours"`
+	Airfields      []airfieldData `json:"airfields"`
+}
+
+// LoadAtcMap reads an airfield data file in the same format as the bundled terrains
+func LoadAtcMap(path string) (AtcMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return AtcMap{}, fmt.Errorf("failed to read map file: %w", err)
+	}
+	return parseAtcMap(data)
+}
+
+// LoadTerrainMap loads one of the bundled DCS terrains, e.g. "caucasus", "syria" or "persiangulf"
+func LoadTerrainMap(terrain string) (AtcMap, error) {
+	data, err := terrainFiles.ReadFile("terrains/" + strings.ToLower(terrain) + ".json")
+	if err != nil {
+		return AtcMap{}, fmt.Errorf("no map data for terrain %s", terrain)
+	}
+	return parseAtcMap(data)
+}
+
+func parseAtcMap(data []byte) (AtcMap, error) {
+	var terrain terrainData
+	if err := json.Unmarshal(data, &terrain); err != nil {
+		return AtcMap{}, fmt.Errorf("failed to parse map file: %w", err)
+	}
+
+	atcMap := AtcMap{
+		Terrain:   terrain.Terrain,
+		UtcOffset: time.Duration(terrain.UtcOffsetHours * float64(time.Hour)),
+		Airfields: make([]*Airfield, 0, len(terrain.Airfields)),
+	}
+	for _, airfieldData := range terrain.Airfields {
+		airfield, err := airfieldData.toAirfield()
+		if err != nil {
+			return AtcMap{}, err
+		}
+		atcMap.Airfields = append(atcMap.Airfields, airfield)
+	}
+	return atcMap, nil
+}
+
+func (p latLon) toPoint() orb.Point {
+	return orb.Point{p.Lon, p.Lat}
+}
+
+func toFrequencies(mhz []float64) []unit.Frequency {
+	frequencies := make([]unit.Frequency, 0, len(mhz))
+	for _, f := range mhz {
+		frequencies = append(frequencies, unit.Frequency(f)*unit.Megahertz)
+	}
+	return frequencies
+}
+
+func (d airfieldData) toAirfield() (*Airfield, error) {
+	if d.Name == "" {
+		return nil, fmt.Errorf("airfield without a name")
+	}
+
+	airfield := &Airfield{
+		Name:      d.Name,
+		Aliases:   d.Aliases,
+		Position:  d.Position.toPoint(),
+		Elevation: unit.Length(d.Elevation) * unit.Foot,
+		Runways:   make([]Runway, 0, len(d.Runways)),
+		Frequencies: AirfieldFrequencies{
+			Tower:     toFrequencies(d.Frequencies.Tower),
+			Ground:    toFrequencies(d.Frequencies.Ground),
+			Approach:  toFrequencies(d.Frequencies.Approach),
+			Departure: toFrequencies(d.Frequencies.Departure),
+			ATIS:      toFrequencies(d.Frequencies.ATIS),
+		},
+	}
+
+	for _, runwayData := range d.Runways {
+		if len(runwayData.Ends) != 2 {
+			return nil, fmt.Errorf("runway at %s must have two ends", d.Name)
+		}
+
+		center := airfield.Position
+		if runwayData.Center != nil {
+			center = runwayData.Center.toPoint()
+		}
+
+		length := unit.Length(runwayData.Length) * unit.Meter
+		heading := bearings.NewTrueBearing(unit.Angle(runwayData.Heading) * unit.Degree)
+		reciprocal := heading.Reciprocal()
+		if runwayData.Offset != 0 {
+			right := bearings.NewTrueBearing(unit.Angle(runwayData.Heading+90) * unit.Degree)
+			center = spatial.PointAtBearingAndDistance(center, right, unit.Length(runwayData.Offset)*unit.Meter)
+		}
+
+		// you land on the threshold at the opposite end of the runway from where you're heading
+		airfield.Runways = append(airfield.Runways, Runway{
+			Ends: [2]RunwayEnd{
+				{
+					Designator: runwayData.Ends[0],
+					Heading:    heading,
+					Threshold:  spatial.PointAtBearingAndDistance(center, reciprocal, length/2),
+				},
+				{
+					Designator: runwayData.Ends[1],
+					Heading:    reciprocal,
+					Threshold:  spatial.PointAtBearingAndDistance(center, heading, length/2),
+				},
+			},
+			Length: length,
+		})
+	}
+
+	if d.Taxiways != nil {
+		taxiways, err := d.Taxiways.toTaxiNetwork(d.Name)
+		if err != nil {
+			return nil, err
+		}
+		airfield.Taxiways = taxiways
+	}
+
+	return airfield, nil
+}
+
+// ActiveRunway returns the runway end in use for takeoff and landing
+func (a *Airfield) ActiveRunway() *RunwayEnd {
+	if a.activeRunway != nil {
+		return a.activeRunway
+	}
+	if len(a.Runways) == 0 {
+		return nil
+	}
+	return &a.Runways[0].Ends[0]
+}
+
+func (a *Airfield) SetActiveRunway(end *RunwayEnd) {
+	a.activeRunway = end
+}
+
+// NearestAirfield returns the closest airfield to the point, or nil if the map is empty
+func (m *AtcMap) NearestAirfield(point orb.Point) (*Airfield, unit.Length) {
+	var nearest *Airfield
+	var nearestDistance unit.Length
+	for _, airfield := range m.Airfields {
+		distance := spatial.Distance(point, airfield.Position)
+		if nearest == nil || distance < nearestDistance {
+			nearest = airfield
+			nearestDistance = distance
+		}
+	}
+	return nearest, nearestDistance
+}
+
+// AirfieldBySpokenName finds the airfield named in a transcript such as "anapa tower, uzi two one, radio check"
+func (m *AtcMap) AirfieldBySpokenName(text string) *Airfield {
+	words := strings.Fields(normalizeSpokenName(text))
+	padded := " " + strings.Join(words, " ") + " "
+
+	// prefer the longest match so "krasnodar center" wins over "krasnodar"
+	var best *Airfield
+	bestLength := 0
+	for _, airfield := range m.Airfields {
+		for _, name := range append([]string{airfield.Name}, airfield.Aliases...) {
+			normalized := normalizeSpokenName(name)
+			if normalized == "" {
+				continue
+			}
+			if strings.Contains(padded, " "+normalized+" ") && len(normalized) > bestLength {
+				best = airfield
+				bestLength = len(normalized)
+			}
+		}
+	}
+	return best
+}
+
+func (m *AtcMap) AirfieldByName(name string) *Airfield {
+	normalized := normalizeSpokenName(name)
+	for _, airfield := range m.Airfields {
+		if normalizeSpokenName(airfield.Name) == normalized {
+			return airfield
+		}
+	}
+	return nil
+}
+
+func normalizeSpokenName(name string) string {
+	name = strings.ToLower(name)
+	name = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return ' '
+	}, name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// RunwayEndByHeading returns the runway end best aligned with the heading, e.g. to work out which
+// runway an aircraft is departing from or lined up on
+func (a *Airfield) RunwayEndByHeading(heading bearings.Bearing) *RunwayEnd {
+	var best *RunwayEnd
+	bestDifference := math.MaxFloat64
+	for i := range a.Runways {
+		for j := range a.Runways[i].Ends {
+			end := &a.Runways[i].Ends[j]
+			difference := HeadingDifference(end.Heading, heading)
+			if difference < bestDifference {
+				best = end
+				bestDifference = difference
+			}
+		}
+	}
+	return best
+}
+
+// RunwayEndByDesignator finds a runway end by its spoken number, ignoring leading zeros
+func (a *Airfield) RunwayEndByDesignator(designator string) *RunwayEnd {
+	designator = strings.TrimLeft(strings.ToUpper(designator), "0")
+	for i := range a.Runways {
+		for j := range a.Runways[i].Ends {
+			end := &a.Runways[i].Ends[j]
+			if strings.TrimLeft(end.Designator, "0") == designator {
+				return end
+			}
+		}
+	}
+	return nil
+}
+
+// HeadingDifference returns the absolute difference between two bearings in degrees, in range [0, 180]
+func HeadingDifference(a bearings.Bearing, b bearings.Bearing) float64 {
+	difference := math.Abs(a.Degrees() - b.Degrees())
+	if difference > 180 {
+		difference = 360 - difference
+	}
+	return difference
 }
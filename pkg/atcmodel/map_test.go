@@ -0,0 +1,183 @@
+package atcmodel
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dharmab/skyeye/pkg/bearings"
+	"github.com/dharmab/skyeye/pkg/spatial"
+	"github.com/martinlindhe/unit"
+	"github.com/paulmach/orb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadTerrainMaps(t *testing.T) {
+	for _, terrain := range []string{"caucasus", "syria", "persiangulf"} {
+		t.Run(terrain, func(t *testing.T) {
+			atcMap, err := LoadTerrainMap(terrain)
+			assert.Nil(t, err)
+			assert.NotEmpty(t, atcMap.Airfields)
+			for _, airfield := range atcMap.Airfields {
+				assert.NotEmpty(t, airfield.Runways, airfield.Name)
+				assert.NotEmpty(t, airfield.Frequencies.Tower, airfield.Name)
+			}
+		})
+	}
+
+	_, err := LoadTerrainMap("marianas")
+	assert.NotNil(t, err)
+}
+
+func TestZuluTime(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	assert.Equal(t, 4*time.Hour, atcMap.UtcOffset)
+
+	model := NewAtcModel(atcMap)
+	assert.True(t, model.ZuluTime().IsZero(), "no telemetry yet")
+
+	// DCS labels mission time as UTC even though it's local
+	model.GameTime = time.Date(2024, 6, 1, 2, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 5, 31, 22, 30, 0, 0, time.UTC), model.ZuluTime())
+}
+
+func TestLoadAtcMap(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "custom.json")
+	err := os.WriteFile(path, []byte(`{
+		"terrain": "Custom",
+		"airfields": [{
+			"name": "Test Field",
+			"position": {"lat": 45, "lon": 37},
+			"elevation_ft": 100,
+			"runways": [{"ends": ["09", "27"], "heading": 90, "length_m": 2000}],
+			"frequencies_mhz": {"tower": [250.0]}
+		}]
+	}`), 0644)
+	assert.Nil(err)
+
+	atcMap, err := LoadAtcMap(path)
+	assert.Nil(err)
+	assert.Equal("Custom", atcMap.Terrain)
+	assert.Len(atcMap.Airfields, 1)
+
+	airfield := atcMap.Airfields[0]
+	assert.InDelta(100, airfield.Elevation.Feet(), 0.01)
+	assert.InDelta(250000000, float64(airfield.Frequencies.Tower[0]), 1)
+
+	// landing on 09 means touching down at the western threshold
+	runway := airfield.Runways[0]
+	assert.Equal("09", runway.Ends[0].Designator)
+	assert.InDelta(90, runway.Ends[0].Heading.Degrees(), 0.01)
+	assert.Less(runway.Ends[0].Threshold.Lon(), 37.0)
+	assert.InDelta(270, runway.Ends[1].Heading.Degrees(), 0.01)
+	assert.Greater(runway.Ends[1].Threshold.Lon(), 37.0)
+	assert.InDelta(2000, spatial.Distance(runway.Ends[0].Threshold, runway.Ends[1].Threshold).Meters(), 1)
+
+	_, err = LoadAtcMap(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NotNil(err)
+}
+
+func TestNearestAirfield(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+
+	// a few miles west of Kobuleti, out over the sea
+	point := spatial.PointAtBearingAndDistance(orb.Point{41.8624, 41.9296}, bearings.NewTrueBearing(270*unit.Degree), 5*unit.NauticalMile)
+	airfield, distance := atcMap.NearestAirfield(point)
+	assert.Equal(t, "Kobuleti", airfield.Name)
+	assert.InDelta(t, 5, distance.NauticalMiles(), 0.1)
+
+	empty := AtcMap{}
+	airfield, _ = empty.NearestAirfield(point)
+	assert.Nil(t, airfield)
+}
+
+func TestAirfieldBySpokenName(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{text: "anapa tower uzi two one radio check", expected: "Anapa"},
+		{text: "Kobuleti ground, Hawg 3-1, request taxi", expected: "Kobuleti"},
+		{text: "krasnodar center tower enfield one one", expected: "Krasnodar Center"},
+		{text: "lochini tower colt one one inbound", expected: "Tbilisi"},
+		{text: "mineralnye vody approach", expected: "Mineralnye Vody"},
+		{text: "tower uzi two one radio check", expected: ""},
+		// partial words shouldn't match
+		{text: "anapatower", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			airfield := atcMap.AirfieldBySpokenName(tt.text)
+			if tt.expected == "" {
+				assert.Nil(t, airfield)
+			} else {
+				assert.NotNil(t, airfield)
+				assert.Equal(t, tt.expected, airfield.Name)
+			}
+		})
+	}
+}
+
+func TestRunwayEndLookup(t *testing.T) {
+	assert := assert.New(t)
+
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(err)
+	anapa := atcMap.AirfieldByName("anapa")
+	assert.NotNil(anapa)
+
+	assert.Equal("22", anapa.RunwayEndByHeading(bearings.NewTrueBearing(200*unit.Degree)).Designator)
+	assert.Equal("04", anapa.RunwayEndByHeading(bearings.NewTrueBearing(10*unit.Degree)).Designator)
+
+	assert.Equal("04", anapa.RunwayEndByDesignator("4").Designator)
+	assert.Equal("22", anapa.RunwayEndByDesignator("22").Designator)
+	assert.Nil(anapa.RunwayEndByDesignator("13"))
+
+	pashkovsky := atcMap.AirfieldByName("Krasnodar Pashkovsky")
+	assert.Equal("23L", pashkovsky.RunwayEndByDesignator("23l").Designator)
+}
+
+func TestParallelRunways(t *testing.T) {
+	atcMap, err := LoadTerrainMap("persiangulf")
+	assert.Nil(t, err)
+
+	center := func(runway Runway) orb.Point {
+		a, b := runway.Ends[0].Threshold, runway.Ends[1].Threshold
+		return orb.Point{(a[0] + b[0]) / 2, (a[1] + b[1]) / 2}
+	}
+	for _, tt := range []struct {
+		airfield   string
+		separation unit.Length
+	}{
+		{airfield: "Dubai", separation: 384 * unit.Meter},
+		{airfield: "Sharjah", separation: 200 * unit.Meter},
+		{airfield: "Abu Dhabi", separation: 2100 * unit.Meter},
+	} {
+		airfield := atcMap.AirfieldByName(tt.airfield)
+		assert.Len(t, airfield.Runways, 2, tt.airfield)
+		left, right := airfield.Runways[0], airfield.Runways[1]
+		assert.True(t, strings.HasSuffix(left.Ends[0].Designator, "L"), tt.airfield)
+		assert.True(t, strings.HasSuffix(right.Ends[0].Designator, "R"), tt.airfield)
+
+		// looking down the runway, the right one is off to the right
+		toRight := spatial.TrueBearing(center(left), center(right))
+		rightOfRunway := bearings.NewTrueBearing(unit.Angle(left.Ends[0].Heading.Degrees()+90) * unit.Degree)
+		assert.InDelta(t, 0, HeadingDifference(toRight, rightOfRunway), 1, tt.airfield)
+		assert.InDelta(t, tt.separation.Meters(), spatial.Distance(center(left), center(right)).Meters(), 5, tt.airfield)
+	}
+}
+
+func TestHeadingDifference(t *testing.T) {
+	assert.InDelta(t, 20, HeadingDifference(bearings.NewTrueBearing(350*unit.Degree), bearings.NewTrueBearing(10*unit.Degree)), 0.001)
+	assert.InDelta(t, 180, HeadingDifference(bearings.NewTrueBearing(90*unit.Degree), bearings.NewTrueBearing(270*unit.Degree)), 0.001)
+}
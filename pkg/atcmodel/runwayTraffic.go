@@ -0,0 +1,65 @@
+package atcmodel
+
+type RunwayTraffic int
+
+const (
+	RunwayClear RunwayTraffic = iota
+	TrafficOnRunway
+	TrafficLanding
+)
+
+func (t RunwayTraffic) String() string {
+	switch t {
+	case TrafficOnRunway:
+		return "on runway"
+	case TrafficLanding:
+		return "landing"
+	default:
+		return "clear"
+	}
+}
+
+// RunwayOf returns the runway the end belongs to
+func (a *Airfield) RunwayOf(end *RunwayEnd) *Runway {
+	for i := range a.Runways {
+		for j := range a.Runways[i].Ends {
+			if &a.Runways[i].Ends[j] == end {
+				return &a.Runways[i]
+			}
+		}
+	}
+	return nil
+}
+
+// RunwayTrafficAt looks through every tracked plane for anything on the runway or on final to either end of
+// it. Planes in ignore (e.g. the caller and their wingmen) don't count. Returns the first conflicting plane.
+func (a *AtcModel) RunwayTrafficAt(airfield *Airfield, end *RunwayEnd, ignore map[uint64]bool) (RunwayTraffic, uint64) {
+	runway := airfield.RunwayOf(end)
+	if runway == nil {
+		return RunwayClear, 0
+	}
+
+	onRunway := uint64(0)
+	foundOnRunway := false
+	for planeId, planeData := range a.AllPlaneData {
+		if ignore[planeId] {
+			continue
+		}
+
+		state, tracked := a.PlaneStates[planeId]
+		if tracked && state.State() == StateOnFinal && airfield.RunwayOf(state.Runway()) == runway {
+			// landing traffic is the bigger problem, report it first
+			return TrafficLanding, planeId
+		}
+
+		if !foundOnRunway && !isAirborne(planeData.Frame, airfield) && runway.Contains(planeData.Frame.Point) {
+			onRunway = planeId
+			foundOnRunway = true
+		}
+	}
+
+	if foundOnRunway {
+		return TrafficOnRunway, onRunway
+	}
+	return RunwayClear, 0
+}
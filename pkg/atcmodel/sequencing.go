@@ -0,0 +1,177 @@
+package atcmodel
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dharmab/skyeye/pkg/spatial"
+	"github.com/martinlindhe/unit"
+)
+
+const (
+	// used for planes we don't have a speed for yet, or that are slowing down in the pattern
+	MIN_APPROACH_SPEED = 120 * unit.Knot
+	// a plane has to be this much sooner than the one ahead to take its place, so the order doesn't flip
+	// back and forth between two planes with about the same ETA
+	SEQUENCE_HYSTERESIS = 20 * time.Second
+)
+
+var cardinalDirections = []string{"north", "northeast", "east", "southeast", "south", "southwest", "west", "northwest"}
+
+// Resequence works out the landing order for every runway with arrivals and tells pilots we're talking to
+// when their number changes. Runs on every telemetry update.
+func (a *AtcModel) Resequence() {
+	members := a.sequenceMembers()
+
+	for runway := range a.Sequences {
+		if _, ok := members[runway]; !ok {
+			delete(a.Sequences, runway)
+		}
+	}
+	for runway, planes := range members {
+		a.Sequences[runway] = a.orderSequence(runway, planes)
+	}
+
+	for runway, sequence := range a.Sequences {
+		for i, planeId := range sequence {
+			a.announceNumber(planeId, runway, sequence, i)
+		}
+	}
+}
+
+// sequenceMembers finds every plane landing on each runway: arrivals we're talking to, and anyone else
+// who's already on final
+func (a *AtcModel) sequenceMembers() map[*RunwayEnd][]uint64 {
+	members := make(map[*RunwayEnd][]uint64)
+	for planeId, state := range a.PlaneStates {
+		if _, ok := a.AllPlaneData[planeId]; !ok {
+			continue
+		}
+
+		var runway *RunwayEnd
+		if arrival, ok := a.Arrivals[planeId]; ok && state.State().IsAirborne() {
+			runway = arrival.Runway
+		} else if state.State() == StateOnFinal {
+			runway = state.Runway()
+		}
+
+		if runway != nil {
+			members[runway] = append(members[runway], planeId)
+		}
+	}
+	return members
+}
+
+// orderSequence keeps the previous order where it can, adds newcomers by ETA and lets planes move up only
+// if they're clearly ahead of the plane in front
+func (a *AtcModel) orderSequence(runway *RunwayEnd, planes []uint64) []uint64 {
+	etas := make(map[uint64]time.Duration, len(planes))
+	for _, planeId := range planes {
+		etas[planeId] = a.timeToThreshold(planeId, runway)
+	}
+
+	order := []uint64{}
+	for _, planeId := range a.Sequences[runway] {
+		if _, ok := etas[planeId]; ok {
+			order = append(order, planeId)
+			delete(etas, planeId)
+		}
+	}
+	newcomers := []uint64{}
+	for planeId := range etas {
+		newcomers = append(newcomers, planeId)
+	}
+	sort.Slice(newcomers, func(i, j int) bool {
+		if etas[newcomers[i]] != etas[newcomers[j]] {
+			return etas[newcomers[i]] < etas[newcomers[j]]
+		}
+		return newcomers[i] < newcomers[j]
+	})
+
+	for _, planeId := range newcomers {
+		// slot the newcomer in behind everyone who gets there before it
+		eta := etas[planeId]
+		position := len(order)
+		for position > 0 && eta+SEQUENCE_HYSTERESIS < a.timeToThreshold(order[position-1], runway) {
+			position--
+		}
+		order = append(order[:position], append([]uint64{planeId}, order[position:]...)...)
+	}
+
+	for swapped := true; swapped; {
+		swapped = false
+		for i := 0; i+1 < len(order); i++ {
+			if a.timeToThreshold(order[i+1], runway)+SEQUENCE_HYSTERESIS < a.timeToThreshold(order[i], runway) {
+				order[i], order[i+1] = order[i+1], order[i]
+				swapped = true
+			}
+		}
+	}
+
+	return order
+}
+
+func (a *AtcModel) timeToThreshold(planeId uint64, runway *RunwayEnd) time.Duration {
+	distance := spatial.Distance(a.AllPlaneData[planeId].Frame.Point, runway.Threshold)
+	speed := MIN_APPROACH_SPEED
+	if state, ok := a.PlaneStates[planeId]; ok && state.GroundSpeed() > speed {
+		speed = state.GroundSpeed()
+	}
+	return time.Duration(distance.Meters() / speed.MetersPerSecond() * float64(time.Second))
+}
+
+func (a *AtcModel) announceNumber(planeId uint64, runway *RunwayEnd, sequence []uint64, index int) {
+	arrival, ok := a.Arrivals[planeId]
+	if !ok || arrival.Runway != runway {
+		return
+	}
+
+	number := index + 1
+	following := uint64(0)
+	if index > 0 {
+		following = sequence[index-1]
+	}
+	if arrival.Number == number && arrival.following == following {
+		return
+	}
+	arrival.Number = number
+	arrival.following = following
+
+	// planes on final hear their number with the landing clearance
+	if arrival.ClearedToLand || a.PlaneStates[planeId].State() == StateOnFinal {
+		return
+	}
+
+	callsign := arrival.callsign()
+	if number == 1 {
+		a.sayToArrival(arrival, fmt.Sprintf("%s, number one for runway %s", callsign, runway.Designator))
+		return
+	}
+	a.sayToArrival(arrival, fmt.Sprintf("%s, number %s, follow the %s", callsign, SpokenNumber(number),
+		a.describeTraffic(following, runway)))
+}
+
+// describeTraffic says which plane to follow and where it is, e.g. "F-16 on two-mile final"
+func (a *AtcModel) describeTraffic(planeId uint64, runway *RunwayEnd) string {
+	plane := a.AllPlaneData[planeId]
+	planeType := spokenPlaneType(plane.Labels.ACMIName)
+	distance := spatial.Distance(plane.Frame.Point, runway.Threshold)
+	miles := int(math.Max(1, math.Round(distance.NauticalMiles())))
+
+	switch a.PlaneStates[planeId].State() {
+	case StateOnFinal:
+		return fmt.Sprintf("%s on %s-mile final", planeType, SpokenNumber(miles))
+	case StateInPattern:
+		return fmt.Sprintf("%s in the pattern", planeType)
+	default:
+		direction := cardinalDirection(spatial.TrueBearing(runway.Threshold, plane.Frame.Point).Degrees())
+		return fmt.Sprintf("%s %s miles %s", planeType, SpokenNumber(miles), direction)
+	}
+}
+
+func cardinalDirection(degrees float64) string {
+	index := int(math.Round(math.Mod(degrees+360, 360)/45)) % len(cardinalDirections)
+	return cardinalDirections[index]
+}
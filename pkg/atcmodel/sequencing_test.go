@@ -0,0 +1,98 @@
+package atcmodel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/martinlindhe/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResequence(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	model := NewAtcModel(atcMap)
+	model.messageOut = make(chan message.OutgoingMessage, 10)
+
+	anapa := model.Map.AirfieldByName("Anapa")
+	runway04 := anapa.RunwayEndByDesignator("04")
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	addArrival := func(planeId uint64, callsign string) {
+		model.AddArrival(&Arrival{PlaneId: planeId, Airfield: anapa, Runway: runway04, Entry: EntryStraightIn,
+			Request: message.Message[string]{Context: context.Background(), ClientName: callsign}})
+	}
+
+	// an F-16 nobody's talking to on short final, and two A-10s coming in behind it
+	updatePlane(model, 2, now, pointFrom(runway04.Threshold, 222*unit.Degree, 2*unit.NauticalMile), 42*unit.Degree, 600*unit.Foot)
+	model.AllPlaneData[2].Labels.ACMIName = "F-16C_50"
+	updatePlane(model, 1, now, pointFrom(runway04.Threshold, 222*unit.Degree, 8*unit.NauticalMile), 42*unit.Degree, 3000*unit.Foot)
+	model.AllPlaneData[1].Labels.ACMIName = "A-10C_2"
+	updatePlane(model, 3, now, pointFrom(runway04.Threshold, 270*unit.Degree, 15*unit.NauticalMile), 90*unit.Degree, 5000*unit.Foot)
+	model.AllPlaneData[3].Labels.ACMIName = "A-10C_2"
+	addArrival(1, "hawg31")
+	addArrival(3, "hawg41")
+
+	model.Resequence()
+	assert.Equal(t, []uint64{2, 1, 3}, model.Sequences[runway04])
+	assert.ElementsMatch(t, []string{
+		"hawg31, number two, follow the F-16 on two-mile final",
+		"hawg41, number three, follow the A-10 eight miles southwest",
+	}, receiveAll(model.messageOut))
+
+	// nothing changed, nothing to say
+	model.Resequence()
+	assert.Empty(t, receiveAll(model.messageOut))
+
+	// the F-16 lands and is off the runway, and hawg41 cuts in front of hawg31
+	now = now.Add(time.Minute)
+	updatePlane(model, 2, now, pointFrom(runway04.Threshold, 42*unit.Degree, 300*unit.Meter), 42*unit.Degree, 0)
+	updatePlane(model, 2, now, pointFrom(pointFrom(runway04.Threshold, 42*unit.Degree, 900*unit.Meter), 312*unit.Degree, 100*unit.Meter), 312*unit.Degree, 0)
+	updatePlane(model, 1, now, pointFrom(runway04.Threshold, 222*unit.Degree, 7*unit.NauticalMile), 42*unit.Degree, 2500*unit.Foot)
+	updatePlane(model, 3, now, pointFrom(runway04.Threshold, 222*unit.Degree, 3*unit.NauticalMile), 42*unit.Degree, 1000*unit.Foot)
+	model.Resequence()
+	assert.Equal(t, []uint64{3, 1}, model.Sequences[runway04])
+	// hawg41 is on final so gets its number with the landing clearance
+	assert.ElementsMatch(t, []string{
+		"hawg31, number two, follow the A-10 on three-mile final",
+		"hawg41, runway 04, cleared to land, wind calm",
+	}, receiveAll(model.messageOut))
+	assert.Equal(t, 1, model.LandingNumber(3, runway04))
+}
+
+func TestResequence_Hysteresis(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	model := NewAtcModel(atcMap)
+	model.messageOut = make(chan message.OutgoingMessage, 10)
+
+	anapa := model.Map.AirfieldByName("Anapa")
+	runway04 := anapa.RunwayEndByDesignator("04")
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+
+	updatePlane(model, 1, now, pointFrom(runway04.Threshold, 222*unit.Degree, 10*unit.NauticalMile), 42*unit.Degree, 3000*unit.Foot)
+	updatePlane(model, 2, now, pointFrom(runway04.Threshold, 270*unit.Degree, 10.2*unit.NauticalMile), 90*unit.Degree, 3000*unit.Foot)
+	for _, planeId := range []uint64{1, 2} {
+		model.AddArrival(&Arrival{PlaneId: planeId, Airfield: anapa, Runway: runway04, Entry: EntryOverhead,
+			Request: message.Message[string]{Context: context.Background(), ClientName: "test"}})
+	}
+	model.Resequence()
+	assert.Equal(t, []uint64{1, 2}, model.Sequences[runway04])
+
+	// a few seconds sooner isn't enough to swap places
+	now = now.Add(10 * time.Second)
+	updatePlane(model, 1, now, pointFrom(runway04.Threshold, 222*unit.Degree, 9.9*unit.NauticalMile), 42*unit.Degree, 3000*unit.Foot)
+	updatePlane(model, 2, now, pointFrom(runway04.Threshold, 270*unit.Degree, 9.85*unit.NauticalMile), 90*unit.Degree, 3000*unit.Foot)
+	model.Resequence()
+	assert.Equal(t, []uint64{1, 2}, model.Sequences[runway04])
+}
+
+func TestCardinalDirection(t *testing.T) {
+	assert.Equal(t, "north", cardinalDirection(0))
+	assert.Equal(t, "north", cardinalDirection(359))
+	assert.Equal(t, "northeast", cardinalDirection(40))
+	assert.Equal(t, "southwest", cardinalDirection(222))
+	assert.Equal(t, "west", cardinalDirection(270))
+}
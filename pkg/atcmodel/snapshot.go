@@ -0,0 +1,83 @@
+package atcmodel
+
+import (
+	"sort"
+	"time"
+)
+
+// how often the model publishes a fresh snapshot for readers outside the model loop
+const SNAPSHOT_INTERVAL = 2 * time.Second
+
+// Snapshot is a compact, read-only summary of the traffic picture. Only the model loop can touch the model, so
+// anything else that wants to know what's going on, like the LLM intent parser, reads the latest snapshot.
+type Snapshot struct {
+	// airfields with traffic at them
+	Airfields []AirfieldSnapshot `json:"airfields"`
+	Aircraft  []AircraftSnapshot `json:"aircraft"`
+}
+
+type AirfieldSnapshot struct {
+	Name         string `json:"name"`
+	ActiveRunway string `json:"active_runway,omitempty"`
+	Wind         string `json:"wind"`
+}
+
+type AircraftSnapshot struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	State    string `json:"state"`
+	Airfield string `json:"airfield,omitempty"`
+	Runway   string `json:"runway,omitempty"`
+}
+
+// Snapshot summarizes the model. Call it from the model loop, everything else should use LatestSnapshot.
+func (a *AtcModel) Snapshot() *Snapshot {
+	snapshot := &Snapshot{Airfields: []AirfieldSnapshot{}, Aircraft: []AircraftSnapshot{}}
+	airfields := map[*Airfield]bool{}
+	for id, plane := range a.AllPlaneData {
+		aircraft := AircraftSnapshot{
+			Name:  plane.Labels.Name,
+			Type:  plane.Labels.ACMIName,
+			State: StateUnknown.String(),
+		}
+		if state, ok := a.PlaneStates[id]; ok {
+			aircraft.State = state.State().String()
+			if airfield := state.Airfield(); airfield != nil {
+				aircraft.Airfield = airfield.Name
+				airfields[airfield] = true
+			}
+			if runway := state.Runway(); runway != nil {
+				aircraft.Runway = runway.Designator
+			}
+		}
+		snapshot.Aircraft = append(snapshot.Aircraft, aircraft)
+	}
+	sort.Slice(snapshot.Aircraft, func(i, j int) bool {
+		return snapshot.Aircraft[i].Name < snapshot.Aircraft[j].Name
+	})
+
+	for _, airfield := range a.Map.Airfields {
+		if !airfields[airfield] {
+			continue
+		}
+		summary := AirfieldSnapshot{Name: airfield.Name, Wind: a.WeatherAt(airfield).WindPhrase(a.Declination(airfield))}
+		if runway := airfield.ActiveRunway(); runway != nil {
+			summary.ActiveRunway = runway.Designator
+		}
+		snapshot.Airfields = append(snapshot.Airfields, summary)
+	}
+	return snapshot
+}
+
+// LatestSnapshot is the snapshot the model loop last published, safe to call from any goroutine. It's empty
+// until the model starts.
+func (a *AtcModel) LatestSnapshot() *Snapshot {
+	if snapshot := a.latestSnapshot.Load(); snapshot != nil {
+		return snapshot
+	}
+	return &Snapshot{Airfields: []AirfieldSnapshot{}, Aircraft: []AircraftSnapshot{}}
+}
+
+func (a *AtcModel) publishSnapshot() {
+	a.latestSnapshot.Store(a.Snapshot())
+}
@@ -0,0 +1,41 @@
+package atcmodel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dharmab/skyeye/pkg/sim"
+	"github.com/dharmab/skyeye/pkg/trackfiles"
+	"github.com/martinlindhe/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	atcMap, err := LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	model := NewAtcModel(atcMap)
+	assert.Empty(t, model.LatestSnapshot().Aircraft, "nothing published yet")
+
+	anapa := model.Map.AirfieldByName("Anapa")
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	model.AllPlaneData[1] = &sim.Updated{Labels: trackfiles.Labels{ID: 1, Name: "Hawg 3-1", ACMIName: "A-10C_2"}}
+	updatePlane(model, 1, now, anapa.Position, 0, 0)
+	updatePlane(model, 1, now.Add(2*time.Second), anapa.Position, 0, 0)
+	model.AllPlaneData[2] = &sim.Updated{Labels: trackfiles.Labels{ID: 2, Name: "Colt 1-1", ACMIName: "F-16C_50"}}
+	updatePlane(model, 2, now, pointFrom(anapa.Position, 270*unit.Degree, 80*unit.NauticalMile), 90*unit.Degree, 20000*unit.Foot)
+
+	model.publishSnapshot()
+	snapshot := model.LatestSnapshot()
+
+	assert.Len(t, snapshot.Aircraft, 2)
+	assert.Equal(t, "Colt 1-1", snapshot.Aircraft[0].Name)
+	assert.Equal(t, "F-16C_50", snapshot.Aircraft[0].Type)
+	assert.Equal(t, "enroute", snapshot.Aircraft[0].State)
+	assert.Equal(t, "Hawg 3-1", snapshot.Aircraft[1].Name)
+	assert.Equal(t, anapa.Name, snapshot.Aircraft[1].Airfield)
+
+	// only airfields with traffic
+	assert.Len(t, snapshot.Airfields, 1)
+	assert.Equal(t, anapa.Name, snapshot.Airfields[0].Name)
+	assert.Equal(t, anapa.ActiveRunway().Designator, snapshot.Airfields[0].ActiveRunway)
+}
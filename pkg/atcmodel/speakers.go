@@ -0,0 +1,83 @@
+package atcmodel
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SRS and telemetry don't share an id, so we have to work out which aircraft each SRS client is flying. The
+// skyeye SRS client doesn't pass on the unit id from SRS sync data, so we go by name, and remember the aircraft
+// whenever a pilot gives a callsign we can match.
+
+// squad tags and other decoration around multiplayer names, e.g. "[JTF-1]" or "=VFS="
+var pilotNameTags = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)|\{[^}]*\}|=[^=]*=|\|.*$`)
+
+// LinkSpeaker remembers that the SRS client is flying this aircraft, e.g. once they've given their callsign
+func (a *AtcModel) LinkSpeaker(clientName string, planeId uint64) {
+	if current, ok := a.Speakers[clientName]; ok && current == planeId {
+		return
+	}
+	log.Info().Msgf("linked SRS client %s to plane %d", clientName, planeId)
+	a.Speakers[clientName] = planeId
+}
+
+// ResolveSpeaker finds the aircraft an SRS client is flying: the one we linked them to before, otherwise the
+// aircraft with the same pilot name
+func (a *AtcModel) ResolveSpeaker(clientName string) (uint64, bool) {
+	if planeId, ok := a.Speakers[clientName]; ok {
+		if _, tracked := a.AllPlaneData[planeId]; tracked {
+			return planeId, true
+		}
+		delete(a.Speakers, clientName)
+	}
+
+	if planeId, ok := a.FindPlaneByName(clientName); ok {
+		return planeId, true
+	}
+
+	// SRS and DCS names often differ by a squad tag, so look for a single aircraft whose name matches once
+	// those are taken off
+	speaker := normalizePilotName(clientName)
+	if speaker == "" {
+		return 0, false
+	}
+	var match uint64
+	matches := 0
+	for name, id := range a.CallsignToId {
+		pilot := normalizePilotName(name)
+		if pilot == speaker || (pilot != "" && containsWords(pilot, speaker)) {
+			match = *id
+			matches++
+		}
+	}
+	if matches != 1 {
+		if matches > 1 {
+			log.Warn().Msgf("SRS client %s matches %d aircraft", clientName, matches)
+		}
+		return 0, false
+	}
+	a.LinkSpeaker(clientName, match)
+	return match, true
+}
+
+func normalizePilotName(name string) string {
+	name = pilotNameTags.ReplaceAllString(name, " ")
+	return normalizeSpokenName(name)
+}
+
+// containsWords is true if either name is a whole word run of the other, e.g. "goldylox" and "goldylox vfs"
+func containsWords(a string, b string) bool {
+	paddedA, paddedB := " "+a+" ", " "+b+" "
+	return strings.Contains(paddedA, paddedB) || strings.Contains(paddedB, paddedA)
+}
+
+// forgetSpeakers drops links to an aircraft that has left
+func (a *AtcModel) forgetSpeakers(planeId uint64) {
+	for clientName, id := range a.Speakers {
+		if id == planeId {
+			delete(a.Speakers, clientName)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package atcmodel
+
+import (
+	"testing"
+
+	"github.com/dharmab/skyeye/pkg/sim"
+	"github.com/dharmab/skyeye/pkg/trackfiles"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSpeaker(t *testing.T) {
+	model := NewAtcModel(AtcMap{})
+	for id, name := range map[uint64]string{1: "[JTF-1] Goldylox", 2: "Hawg 3-1", 3: "=VFS= Maverick", 4: "Maverick | 104th"} {
+		model.AllPlaneData[id] = &sim.Updated{Labels: trackfiles.Labels{ID: id, Name: name}}
+		model.CallsignToId[name] = &id
+	}
+
+	planeId, found := model.ResolveSpeaker("Hawg 3-1")
+	assert.True(t, found)
+	assert.Equal(t, uint64(2), planeId)
+
+	planeId, found = model.ResolveSpeaker("Goldylox")
+	assert.True(t, found, "matches without the squad tag")
+	assert.Equal(t, uint64(1), planeId)
+	assert.Equal(t, uint64(1), model.Speakers["Goldylox"])
+
+	_, found = model.ResolveSpeaker("Maverick")
+	assert.False(t, found, "two pilots called maverick, don't guess")
+
+	_, found = model.ResolveSpeaker("Iceman")
+	assert.False(t, found)
+
+	// once linked it sticks, until the plane leaves
+	model.LinkSpeaker("Iceman", 3)
+	planeId, found = model.ResolveSpeaker("Iceman")
+	assert.True(t, found)
+	assert.Equal(t, uint64(3), planeId)
+
+	model.forgetSpeakers(3)
+	_, found = model.ResolveSpeaker("Iceman")
+	assert.False(t, found)
+}
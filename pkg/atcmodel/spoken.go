@@ -0,0 +1,67 @@
+package atcmodel
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PlaneType is a kind of aircraft, by what it's called on the radio and the type names telemetry reports
+type PlaneType struct {
+	// what controllers call it, e.g. "follow the hornet"
+	Name string
+	// what pilots might call it
+	Spoken    []string
+	ACMINames []string
+}
+
+// PLANE_TYPES are the aircraft we know the radio names for. Anything else is called by its type name without
+// the variant suffix.
+var PLANE_TYPES = []PlaneType{
+	{Name: "A-10", Spoken: []string{"a-10", "a10", "a 10", "hog", "warthog"}, ACMINames: []string{"A-10C_2", "A-10C", "A-10A"}},
+	{Name: "F-16", Spoken: []string{"f-16", "f16", "f 16", "viper"}, ACMINames: []string{"F-16C_50"}},
+	{Name: "hornet", Spoken: []string{"f-18", "f18", "f 18", "hornet"}, ACMINames: []string{"FA-18C_hornet"}},
+	{Name: "F-15", Spoken: []string{"f-15", "f15", "f 15", "eagle"}, ACMINames: []string{"F-15C", "F-15ESE"}},
+	{Name: "tomcat", Spoken: []string{"f-14", "f14", "f 14", "tomcat"}, ACMINames: []string{"F-14B", "F-14A-135-GR"}},
+	{Name: "harrier", Spoken: []string{"harrier", "av-8", "av8"}, ACMINames: []string{"AV8BNA"}},
+	{Name: "mirage", Spoken: []string{"mirage"}, ACMINames: []string{"M-2000C"}},
+	{Name: "apache", Spoken: []string{"apache"}, ACMINames: []string{"AH-64D_BLK_II"}},
+	{Name: "huey", Spoken: []string{"huey"}, ACMINames: []string{"UH-1H"}},
+}
+
+var numberWords = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten",
+	"eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen", "twenty"}
+
+func spokenPlaneType(acmiName string) string {
+	for _, planeType := range PLANE_TYPES {
+		for _, name := range planeType.ACMINames {
+			if name == acmiName {
+				return planeType.Name
+			}
+		}
+	}
+	if name, _, _ := strings.Cut(acmiName, "_"); name != "" {
+		return name
+	}
+	return "traffic"
+}
+
+// SpokenNumber is the number in words up to twenty, digits after that
+func SpokenNumber(n int) string {
+	if n >= 0 && n < len(numberWords) {
+		return numberWords[n]
+	}
+	return strconv.Itoa(n)
+}
+
+// ParseSpokenNumber reads a number said as a word or transcribed as digits
+func ParseSpokenNumber(text string) (int, bool) {
+	for i, word := range numberWords {
+		if text == word {
+			return i, true
+		}
+	}
+	if n, err := strconv.Atoi(text); err == nil {
+		return n, true
+	}
+	return 0, false
+}
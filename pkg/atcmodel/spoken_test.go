@@ -0,0 +1,29 @@
+package atcmodel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpokenPlaneType(t *testing.T) {
+	assert.Equal(t, "A-10", spokenPlaneType("A-10C_2"))
+	assert.Equal(t, "hornet", spokenPlaneType("FA-18C_hornet"))
+	assert.Equal(t, "Su-25T", spokenPlaneType("Su-25T"))
+	assert.Equal(t, "MiG-29A", spokenPlaneType("MiG-29A_variant"))
+	assert.Equal(t, "traffic", spokenPlaneType(""))
+}
+
+func TestSpokenNumber(t *testing.T) {
+	assert.Equal(t, "two", SpokenNumber(2))
+	assert.Equal(t, "twenty", SpokenNumber(20))
+	assert.Equal(t, "21", SpokenNumber(21))
+
+	for _, text := range []string{"four", "4"} {
+		n, ok := ParseSpokenNumber(text)
+		assert.True(t, ok)
+		assert.Equal(t, 4, n)
+	}
+	_, ok := ParseSpokenNumber("hawg")
+	assert.False(t, ok)
+}
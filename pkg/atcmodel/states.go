@@ -2,13 +2,91 @@ package atcmodel
 
 import (
 	"github.com/dharmab/skyeye/pkg/trackfiles"
+	"github.com/martinlindhe/unit"
 )
 
+type FlightState int
+
+const (
+	StateUnknown FlightState = iota
+	StateParked
+	StateTaxiing
+	StateHoldingShort
+	StateLinedUp
+	StateTakeoffRoll
+	StateDeparting
+	StateEnroute
+	StateInbound
+	StateInPattern
+	StateOnFinal
+	StateLanded
+	StateVacated
+)
+
+func (s FlightState) String() string {
+	switch s {
+	case StateParked:
+		return "parked"
+	case StateTaxiing:
+		return "taxiing"
+	case StateHoldingShort:
+		return "holding short"
+	case StateLinedUp:
+		return "lined up"
+	case StateTakeoffRoll:
+		return "takeoff roll"
+	case StateDeparting:
+		return "departing"
+	case StateEnroute:
+		return "enroute"
+	case StateInbound:
+		return "inbound"
+	case StateInPattern:
+		return "in pattern"
+	case StateOnFinal:
+		return "on final"
+	case StateLanded:
+		return "landed"
+	case StateVacated:
+		return "vacated"
+	default:
+		return "unknown"
+	}
+}
+
+func (s FlightState) IsAirborne() bool {
+	switch s {
+	case StateDeparting, StateEnroute, StateInbound, StateInPattern, StateOnFinal:
+		return true
+	default:
+		return false
+	}
+}
+
+// StateTransition is published whenever a plane moves from one FlightState to another
+type StateTransition struct {
+	PlaneId uint64
+	From    FlightState
+	To      FlightState
+	// airfield and runway the plane was at when it transitioned, may be nil
+	Airfield *Airfield
+	Runway   *RunwayEnd
+	Frame    trackfiles.Frame
+}
+
+type StateListener func(transition StateTransition)
+
 type PlaneState interface {
 	UpdateFromTrack(update trackfiles.Frame)
-	TransitionToState()
+	// forces a transition, e.g. when the controller knows better than the track data
+	TransitionToState(state FlightState)
+	State() FlightState
+	Airfield() *Airfield
+	Runway() *RunwayEnd
+	GroundSpeed() unit.Speed
 }
 
 type AtcSquadron struct {
+	LeaderId    uint64
 	PlaneStates map[uint64]PlaneState
 }
@@ -0,0 +1,198 @@
+package atcmodel
+
+import (
+	"container/heap"
+	"fmt"
+
+	"github.com/dharmab/skyeye/pkg/spatial"
+	"github.com/martinlindhe/unit"
+	"github.com/paulmach/orb"
+)
+
+type TaxiNode struct {
+	Name     string
+	Position orb.Point
+	edges    []taxiEdge
+}
+
+type taxiEdge struct {
+	to      *TaxiNode
+	taxiway string
+	length  unit.Length
+}
+
+// TaxiNetwork is the graph of named taxiways at an airfield. Nodes are intersections, hold short points and
+// apron entries; edges are the taxiway segments between them. None of the bundled terrains have taxiway data yet,
+// an airfield data file can add it (see testdata/taxiways.json), and without it the clearance is straight to
+// the runway.
+type TaxiNetwork struct {
+	nodes map[string]*TaxiNode
+	// runway designator -> hold short point for that runway end
+	holdShort map[string]*TaxiNode
+}
+
+type TaxiRoute struct {
+	// taxiway names in the order they're used, without repeats, e.g. ["bravo", "alpha"]
+	Taxiways []string
+	Nodes    []*TaxiNode
+	Length   unit.Length
+}
+
+type taxiNodeData struct {
+	Name string  `json:"name"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+}
+
+func (d taxiNodeData) point() orb.Point {
+	return orb.Point{d.Lon, d.Lat}
+}
+
+type taxiwayData struct {
+	Name  string   `json:"name"`
+	Nodes []string `json:"nodes"`
+}
+
+type taxiNetworkData struct {
+	Nodes     []taxiNodeData    `json:"nodes"`
+	Taxiways  []taxiwayData     `json:"taxiways"`
+	HoldShort map[string]string `json:"hold_short"`
+}
+
+func (d *taxiNetworkData) toTaxiNetwork(airfieldName string) (*TaxiNetwork, error) {
+	network := &TaxiNetwork{
+		nodes:     make(map[string]*TaxiNode, len(d.Nodes)),
+		holdShort: make(map[string]*TaxiNode, len(d.HoldShort)),
+	}
+
+	for _, nodeData := range d.Nodes {
+		network.nodes[nodeData.Name] = &TaxiNode{
+			Name:     nodeData.Name,
+			Position: nodeData.point(),
+		}
+	}
+
+	for _, taxiway := range d.Taxiways {
+		for i := 1; i < len(taxiway.Nodes); i++ {
+			from, fromOk := network.nodes[taxiway.Nodes[i-1]]
+			to, toOk := network.nodes[taxiway.Nodes[i]]
+			if !fromOk || !toOk {
+				return nil, fmt.Errorf("taxiway %s at %s references unknown node", taxiway.Name, airfieldName)
+			}
+			length := spatial.Distance(from.Position, to.Position)
+			from.edges = append(from.edges, taxiEdge{to: to, taxiway: taxiway.Name, length: length})
+			to.edges = append(to.edges, taxiEdge{to: from, taxiway: taxiway.Name, length: length})
+		}
+	}
+
+	for designator, nodeName := range d.HoldShort {
+		node, ok := network.nodes[nodeName]
+		if !ok {
+			return nil, fmt.Errorf("hold short for runway %s at %s references unknown node %s", designator, airfieldName, nodeName)
+		}
+		network.holdShort[designator] = node
+	}
+
+	return network, nil
+}
+
+// NearestNode returns the taxi node closest to the point
+func (n *TaxiNetwork) NearestNode(point orb.Point) *TaxiNode {
+	var nearest *TaxiNode
+	var nearestDistance unit.Length
+	for _, node := range n.nodes {
+		distance := spatial.Distance(point, node.Position)
+		if nearest == nil || distance < nearestDistance {
+			nearest = node
+			nearestDistance = distance
+		}
+	}
+	return nearest
+}
+
+// RouteToRunway finds the shortest taxi route from the point to the hold short point of the runway end
+func (n *TaxiNetwork) RouteToRunway(from orb.Point, runway *RunwayEnd) (*TaxiRoute, error) {
+	destination, ok := n.holdShort[runway.Designator]
+	if !ok {
+		return nil, fmt.Errorf("no hold short point for runway %s", runway.Designator)
+	}
+
+	start := n.NearestNode(from)
+	if start == nil {
+		return nil, fmt.Errorf("taxi network has no nodes")
+	}
+
+	return n.shortestPath(start, destination)
+}
+
+type taxiQueueItem struct {
+	node     *TaxiNode
+	distance unit.Length
+}
+
+type taxiQueue []taxiQueueItem
+
+func (q taxiQueue) Len() int           { return len(q) }
+func (q taxiQueue) Less(i, j int) bool { return q[i].distance < q[j].distance }
+func (q taxiQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *taxiQueue) Push(x any)        { *q = append(*q, x.(taxiQueueItem)) }
+func (q *taxiQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+func (n *TaxiNetwork) shortestPath(start *TaxiNode, destination *TaxiNode) (*TaxiRoute, error) {
+	distances := map[*TaxiNode]unit.Length{start: 0}
+	previous := make(map[*TaxiNode]taxiEdge)
+	visited := make(map[*TaxiNode]bool)
+
+	queue := &taxiQueue{{node: start, distance: 0}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(taxiQueueItem)
+		if visited[item.node] {
+			continue
+		}
+		visited[item.node] = true
+		if item.node == destination {
+			break
+		}
+
+		for _, edge := range item.node.edges {
+			distance := item.distance + edge.length
+			if known, ok := distances[edge.to]; !ok || distance < known {
+				distances[edge.to] = distance
+				// store the edge pointing backwards so the path can be rebuilt from the destination
+				previous[edge.to] = taxiEdge{to: item.node, taxiway: edge.taxiway, length: edge.length}
+				heap.Push(queue, taxiQueueItem{node: edge.to, distance: distance})
+			}
+		}
+	}
+
+	if !visited[destination] {
+		return nil, fmt.Errorf("no taxi route from %s to %s", start.Name, destination.Name)
+	}
+
+	nodes := []*TaxiNode{destination}
+	edges := []string{}
+	for node := destination; node != start; {
+		edge := previous[node]
+		edges = append([]string{edge.taxiway}, edges...)
+		nodes = append([]*TaxiNode{edge.to}, nodes...)
+		node = edge.to
+	}
+
+	taxiways := []string{}
+	for _, taxiway := range edges {
+		if len(taxiways) == 0 || taxiways[len(taxiways)-1] != taxiway {
+			taxiways = append(taxiways, taxiway)
+		}
+	}
+
+	return &TaxiRoute{
+		Taxiways: taxiways,
+		Nodes:    nodes,
+		Length:   distances[destination],
+	}, nil
+}
@@ -0,0 +1,74 @@
+package atcmodel
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/martinlindhe/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteToRunway(t *testing.T) {
+	// a made-up layout around Anapa's runway
+	atcMap, err := LoadAtcMap(filepath.Join("testdata", "taxiways.json"))
+	assert.Nil(t, err)
+
+	airfield := atcMap.AirfieldByName("Test Field")
+	assert.NotNil(t, airfield.Taxiways)
+
+	// parked next to the main apron entry
+	parking := pointFrom(airfield.Position, 132*unit.Degree, 520*unit.Meter)
+
+	tests := []struct {
+		name             string
+		runway           string
+		expectedTaxiways []string
+		expectedStart    string
+		expectedEnd      string
+	}{
+		{name: "to runway 04", runway: "04", expectedTaxiways: []string{"delta", "charlie", "alpha"}, expectedStart: "APRON", expectedEnd: "HS04"},
+		{name: "to runway 22", runway: "22", expectedTaxiways: []string{"bravo", "alpha"}, expectedStart: "APRON", expectedEnd: "HS22"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, err := airfield.Taxiways.RouteToRunway(parking, airfield.RunwayEndByDesignator(tt.runway))
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectedTaxiways, route.Taxiways)
+			assert.Equal(t, tt.expectedStart, route.Nodes[0].Name)
+			assert.Equal(t, tt.expectedEnd, route.Nodes[len(route.Nodes)-1].Name)
+			assert.Greater(t, route.Length, 1000*unit.Meter)
+		})
+	}
+
+	// the western apron is quicker to the runway 04 end via charlie
+	westApron := pointFrom(airfield.Position, 222*unit.Degree, 725*unit.Meter)
+	westApron = pointFrom(westApron, 132*unit.Degree, 450*unit.Meter)
+	route, err := airfield.Taxiways.RouteToRunway(westApron, airfield.RunwayEndByDesignator("04"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"charlie", "alpha"}, route.Taxiways)
+}
+
+func TestRouteToRunway_Errors(t *testing.T) {
+	network := &taxiNetworkData{
+		Nodes: []taxiNodeData{
+			{Name: "A", Lat: 45, Lon: 37},
+			{Name: "B", Lat: 45.001, Lon: 37},
+			{Name: "C", Lat: 45.002, Lon: 37},
+		},
+		Taxiways:  []taxiwayData{{Name: "alpha", Nodes: []string{"A", "B"}}},
+		HoldShort: map[string]string{"09": "C"},
+	}
+	taxiways, err := network.toTaxiNetwork("test")
+	assert.Nil(t, err)
+
+	_, err = taxiways.RouteToRunway(network.Nodes[0].point(), &RunwayEnd{Designator: "27"})
+	assert.NotNil(t, err, "no hold short for runway")
+
+	_, err = taxiways.RouteToRunway(network.Nodes[0].point(), &RunwayEnd{Designator: "09"})
+	assert.NotNil(t, err, "hold short is unreachable")
+
+	network.Taxiways = append(network.Taxiways, taxiwayData{Name: "bravo", Nodes: []string{"B", "D"}})
+	_, err = network.toTaxiNetwork("test")
+	assert.NotNil(t, err, "unknown node")
+}
@@ -0,0 +1,122 @@
+package atcmodel
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/dharmab/skyeye/pkg/bearings"
+	"github.com/martinlindhe/unit"
+)
+
+const (
+	// below this the wind is reported as calm
+	CALM_WIND_SPEED = 3 * unit.Knot
+	// anything better than this is just reported as "10 kilometers or more"
+	UNLIMITED_VISIBILITY = 10 * unit.Kilometer
+	// standard lapse rate, in degrees celsius per foot
+	TEMPERATURE_LAPSE_RATE = 1.98 / 1000
+)
+
+type CloudCover int
+
+const (
+	CloudsClear CloudCover = iota
+	CloudsFew
+	CloudsScattered
+	CloudsBroken
+	CloudsOvercast
+)
+
+func (c CloudCover) String() string {
+	switch c {
+	case CloudsFew:
+		return "few"
+	case CloudsScattered:
+		return "scattered"
+	case CloudsBroken:
+		return "broken"
+	case CloudsOvercast:
+		return "overcast"
+	default:
+		return "clear"
+	}
+}
+
+type Weather struct {
+	// direction the wind is blowing from
+	WindDirection bearings.Bearing
+	WindSpeed     unit.Speed
+	QNH           unit.Pressure
+	Temperature   unit.Temperature
+	Visibility    unit.Length
+	CloudCover    CloudCover
+	// above mean sea level, like the mission editor
+	CloudBase unit.Length
+}
+
+// StandardWeather is ISA at sea level with no wind, used until we know better
+func StandardWeather() Weather {
+	return Weather{
+		WindDirection: bearings.NewTrueBearing(0),
+		WindSpeed:     0,
+		QNH:           unit.Pressure(29.92) * unit.InchOfMercury,
+		Temperature:   unit.FromCelsius(15),
+		Visibility:    UNLIMITED_VISIBILITY,
+		CloudCover:    CloudsClear,
+	}
+}
+
+// HeadwindComponent is how much of the wind is on the nose for a plane heading this way, negative for a tailwind
+func (w Weather) HeadwindComponent(heading bearings.Bearing) unit.Speed {
+	angle := (w.WindDirection.Degrees() - heading.Degrees()) * math.Pi / 180
+	return unit.Speed(float64(w.WindSpeed) * math.Cos(angle))
+}
+
+// AtElevation adjusts the sea level conditions for an airfield at this elevation
+func (w Weather) AtElevation(elevation unit.Length) Weather {
+	w.Temperature = unit.FromCelsius(w.Temperature.Celsius() - elevation.Feet()*TEMPERATURE_LAPSE_RATE)
+	return w
+}
+
+// WindPhrase formats the wind the way a controller says it, magnetic, e.g. "wind 040 at 8" or "wind calm"
+func (w Weather) WindPhrase(declination unit.Angle) string {
+	if w.WindSpeed < CALM_WIND_SPEED {
+		return "wind calm"
+	}
+	direction := w.WindDirection.Magnetic(declination)
+	return fmt.Sprintf("wind %s at %d", direction.String(), int(math.Round(w.WindSpeed.Knots())))
+}
+
+// AltimeterPhrase formats QNH in inches of mercury without the decimal, e.g. "altimeter 2992"
+func (w Weather) AltimeterPhrase() string {
+	return fmt.Sprintf("altimeter %04d", int(math.Round(w.QNH.InchOfMercury()*100)))
+}
+
+// VisibilityPhrase reports visibility in kilometers, e.g. "visibility 4 kilometers"
+func (w Weather) VisibilityPhrase() string {
+	if w.Visibility >= UNLIMITED_VISIBILITY {
+		return "visibility 10 kilometers or more"
+	}
+	return fmt.Sprintf("visibility %d kilometers", int(math.Max(1, math.Floor(w.Visibility.Kilometers()))))
+}
+
+// CloudsPhrase reports the cloud layer above the field in hundreds of feet, e.g. "broken 2500" or "sky clear"
+func (w Weather) CloudsPhrase(fieldElevation unit.Length) string {
+	if w.CloudCover == CloudsClear {
+		return "sky clear"
+	}
+	base := int(math.Round((w.CloudBase-fieldElevation).Feet()/100) * 100)
+	if base < 0 {
+		base = 0
+	}
+	return fmt.Sprintf("%s %d", w.CloudCover, base)
+}
+
+// TemperaturePhrase reports temperature in whole degrees celsius, e.g. "temperature 15" or "temperature minus 3"
+func (w Weather) TemperaturePhrase() string {
+	celsius := int(math.Round(w.Temperature.Celsius()))
+	if celsius < 0 {
+		return fmt.Sprintf("temperature minus %d", -celsius)
+	}
+	return fmt.Sprintf("temperature %d", celsius)
+}
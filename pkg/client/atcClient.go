@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/binary"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
@@ -18,12 +19,25 @@ import (
 )
 
 type AtcApplication struct {
-	Recognizer                 recognizer.Recognizer
-	SpeechSynthesizer          deepgramspeaker.TextToSpeech
+	Recognizer        recognizer.Recognizer
+	SpeechSynthesizer deepgramspeaker.TextToSpeech
+	// makes a synthesizer for each synthesis worker, since one only speaks a message at a time. Without it
+	// every frequency takes turns with SpeechSynthesizer.
+	NewSpeechSynthesizer       func() deepgramspeaker.TextToSpeech
 	CommandProcessor           commands.CommandProcessorInterface
 	EnableTranscriptionLogging bool
 	TelemetryClient            telemetry.Client
-	AtcModel                   atcmodel.AtcModel
+	AtcModel                   *atcmodel.AtcModel
+
+	// how many transmissions are recognized, parsed and spoken at once across all frequencies, zero for the
+	// defaults
+	RecognitionWorkers int
+	ParsingWorkers     int
+	SynthesisWorkers   int
+	// replies wait until nobody has transmitted on their frequency for QuietGap, but no longer than
+	// MaxTransmitDelay. Zero for the defaults.
+	QuietGap         time.Duration
+	MaxTransmitDelay time.Duration
 
 	incomingPlayerCommands chan<- atcmodel.AtcCommand
 
@@ -31,8 +45,14 @@ type AtcApplication struct {
 	simUpdated chan<- sim.Updated
 	simFaded   chan<- sim.Faded
 
-	TranscribedMessages chan message.Message[string]
-	OutgoingMessages    chan message.OutgoingMessage
+	radioClient simpleradio.Client
+
+	lanesLock    sync.Mutex
+	lanes        map[string]*frequencyLane
+	lastHeard    map[string]time.Time
+	recognizers  *workerPool
+	parsers      *workerPool
+	synthesizers chan deepgramspeaker.TextToSpeech
 
 	stopCtx      context.Context
 	stopCancelFn context.CancelFunc
@@ -51,30 +71,27 @@ func (a *AtcApplication) srsLoop(radioClient simpleradio.Client) {
 				continue
 			}
 
-			frequencies := transmission.Frequencies
-			log.Info().Msgf("received transmission from frequency %s", &frequencies[0])
-
-			a.recognizeTransmission(context.Background(), nil, transmission, a.TranscribedMessages)
+			a.heard(transmission.Frequencies)
+			lane := a.laneFor(transmission.Frequencies)
+			log.Info().Msgf("received transmission on %s", lane.name)
+			a.queueTransmission(lane, transmission)
 		}
 	}
 }
 
-func (a *AtcApplication) processTranscriptLoop(radioClient simpleradio.Client) {
-	for {
+// processTranscript parses the transcript and hands the command to the model
+func (a *AtcApplication) processTranscript(msg message.Message[string]) {
+	log.Info().Msg("processing transcription")
+	cmd, err := a.CommandProcessor.ProcessText(context.Background(), &msg)
+	if err != nil {
+		log.Info().Err(err).Msg("command parsing failed")
+	}
+	// if we didn't understand, the command asks the pilot to say again
+	if cmd.ParsedCommand != nil {
+		log.Info().Msgf("sending command to ATC %s", cmd.ParsedCommand)
 		select {
 		case <-a.stopCtx.Done():
-			log.Info().Msg("transcript loop stopping")
-			return
-
-		case msg := <-a.TranscribedMessages:
-			log.Info().Msg("processing transcription")
-			cmd, err := a.CommandProcessor.ProcessText(context.Background(), &msg)
-			if err == nil {
-				log.Info().Msgf("sending command to ATC %s", cmd)
-				a.incomingPlayerCommands <- cmd.ParsedCommand
-			} else {
-				log.Info().Msgf("command parsing failed")
-			}
+		case a.incomingPlayerCommands <- cmd.ParsedCommand:
 		}
 	}
 }
@@ -94,56 +111,24 @@ func convertLinear16ToFloat32(linear16 []byte) []float32 {
 	return float32Data
 }
 
-// do these in serial for now. in theory we can do it in parallel across multiple frequencies
-// but for now block and have just one ATC thread globally (I think it's fine)
-func (a *AtcApplication) processOutgoingAudioLoop(radioClient simpleradio.Client) {
-	for {
-		select {
-		case <-a.stopCtx.Done():
-			log.Info().Msg("outgoing audio loop stopping")
-			return
-
-		case msg := <-a.OutgoingMessages:
-			log.Info().Msg("processing outgoing message")
-
-			audioChannel := make(chan []byte, 5)
-
-			// use text to speech API and pipe results into the radio client
-			err := a.SpeechSynthesizer.GenerateSpeech(msg.Model, msg.Message.Data, audioChannel)
-			log.Info().Msg("done generating speech")
-			if err != nil {
-				log.Error().Err(err).Msg("error generating speech")
-				continue
-			}
+// Send queues the call on its frequency's lane, it never blocks so the model never waits on speech
+func (a *AtcApplication) Send(msg message.OutgoingMessage) {
+	log.Info().Msgf("queueing %s call to %s", msg.Priority, msg.Message.ClientName)
+	msg.Message.SetGameTime(a.gameTime())
+	a.laneFor(msg.Message.Frequencies).queue.Push(msg)
+}
 
-			func() {
-				for {
-					select {
-					case audioBytes := <-audioChannel:
-						if audioBytes == nil {
-							log.Info().Msg("got end of TTS stream")
-							return
-						}
-
-						log.Info().Msg("sending voice transmission")
-						transmission := simpleradio.Transmission{
-							TraceID:     msg.Message.TraceId,
-							ClientName:  msg.Message.ClientName,
-							Frequencies: msg.Message.Frequencies,
-							Audio:       convertLinear16ToFloat32(audioBytes),
-						}
-						radioClient.Transmit(transmission)
-					}
-				}
-			}()
-
-			a.SpeechSynthesizer.Disconnect()
-		}
+// gameTime is the mission time of the latest telemetry, or zero if there isn't any
+func (a *AtcApplication) gameTime() time.Time {
+	if a.TelemetryClient == nil {
+		return time.Time{}
 	}
+	return a.TelemetryClient.Time()
 }
 
+// recognizeTransmission turns the transmission into a transcript, false if it couldn't
 func (a *AtcApplication) recognizeTransmission(processCtx context.Context, requestCtx context.Context,
-	transmission simpleradio.Transmission, out chan<- message.Message[string]) {
+	transmission simpleradio.Transmission) (message.Message[string], bool) {
 
 	recogizerCtx, cancel := context.WithTimeout(processCtx, 30*time.Second)
 	defer func() {
@@ -158,13 +143,13 @@ func (a *AtcApplication) recognizeTransmission(processCtx context.Context, reque
 	text, err := a.Recognizer.Recognize(recogizerCtx, transmission.Audio, a.EnableTranscriptionLogging)
 	if err != nil {
 		log.Error().Err(err).Msg("error recognizing audio sample")
-		return
+		return message.Message[string]{}, false
 	}
 
 	if a.EnableTranscriptionLogging {
 		log.Info().Msgf("recognized text: %s", text)
 	}
-	out <- message.FromTransmission(requestCtx, transmission, text)
+	return message.FromTransmission(requestCtx, transmission, text, a.gameTime()), true
 
 	/*
 		logger := log.With().Stringer("clockTime", time.Since(start)).Logger()
@@ -175,7 +160,7 @@ func (a *AtcApplication) recognizeTransmission(processCtx context.Context, reque
 			logger = logger.With().Str("text", text).Logger()
 		}
 		logger.Info().Msg("recognized audio")
-		out <- AsMessage(requestCtx, text)
+		return AsMessage(requestCtx, text)
 	*/
 }
 
@@ -190,28 +175,56 @@ func (a *AtcApplication) recognizeTransmission(processCtx context.Context, reque
 
 */
 
+// RuleParsers are the parsers that match phrases in the transcript
+func RuleParsers() []commands.PlayerCommandParser {
+	return []commands.PlayerCommandParser{
+		&commands.RadioCheckParser{},
+		&commands.RequestTaxiParser{},
+		&commands.StartUpEnginesParser{},
+		&commands.RequestTakeoffParser{},
+		&commands.InboundForLandingParser{},
+		&commands.RepeatLastCallParser{},
+	}
+}
+
 func LoadCommandProcessor() *commands.CommandProcessor {
 	rand := commands.RealGenerator{}
 	cp := commands.NewCommandProcessor(&rand)
 
-	cp.RegisterParser(&commands.RadioCheckParser{})
+	for _, parser := range RuleParsers() {
+		cp.RegisterParser(parser)
+	}
 
 	return cp
 }
 
 func (a *AtcApplication) Start(srsClient simpleradio.Client) {
 	a.stopCtx, a.stopCancelFn = context.WithCancel(context.Background())
-	a.TranscribedMessages = make(chan message.Message[string], 5)
-	a.OutgoingMessages = make(chan message.OutgoingMessage, 5)
+	a.radioClient = srsClient
+	a.startPipeline()
+
+	simStarted := make(chan sim.Started, 1)
+	simUpdated := make(chan sim.Updated, 100)
+	simFaded := make(chan sim.Faded, 10)
+	playerCommands := make(chan atcmodel.AtcCommand, 5)
+	a.simStarted, a.simUpdated, a.simFaded = simStarted, simUpdated, simFaded
+	a.incomingPlayerCommands = playerCommands
+
+	// the model loop owns all the ATC state, so telemetry, player commands and unprompted calls like the
+	// ATIS all go through it
+	if a.AtcModel != nil {
+		go a.AtcModel.Start(a.stopCtx, simStarted, simUpdated, simFaded, playerCommands, a)
+	}
 
-	go a.TelemetryClient.Run(a.stopCtx, nil)
-	go func() {
-		log.Info().Msg("streaming telemetry data")
-		a.TelemetryClient.Stream(a.stopCtx, nil, a.simStarted, a.simUpdated, a.simFaded)
-	}()
+	if a.TelemetryClient != nil {
+		var wg sync.WaitGroup
+		go a.TelemetryClient.Run(a.stopCtx, &wg)
+		go func() {
+			log.Info().Msg("streaming telemetry data")
+			a.TelemetryClient.Stream(a.stopCtx, &wg, a.simStarted, a.simUpdated, a.simFaded)
+		}()
+	}
 
-	go a.processOutgoingAudioLoop(srsClient)
-	go a.processTranscriptLoop(srsClient)
 	a.srsLoop(srsClient)
 }
 
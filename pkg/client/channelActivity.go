@@ -0,0 +1,76 @@
+package atcclient
+
+import (
+	"time"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/simpleradio/voice"
+	"github.com/rs/zerolog/log"
+)
+
+// SRS won't start transmitting while it's receiving, but as soon as a pilot lets go of the button we'd be on
+// top of their wingman's readback or the next pilot's call. We only hear about a transmission once it's over,
+// so replies wait until nobody has transmitted on their frequency for a moment.
+
+const (
+	DEFAULT_QUIET_GAP = 1 * time.Second
+	// past this the reply goes out anyway, it's no use to anyone once it's stale
+	DEFAULT_MAX_TRANSMIT_DELAY = 10 * time.Second
+)
+
+// heard notes that a transmission just finished on the frequencies
+func (a *AtcApplication) heard(frequencies []voice.Frequency) {
+	now := time.Now()
+	a.lanesLock.Lock()
+	defer a.lanesLock.Unlock()
+	for _, frequency := range frequencies {
+		a.lastHeard[laneName([]voice.Frequency{frequency})] = now
+	}
+}
+
+// lastHeardOn is when a transmission last finished on any of the frequencies
+func (a *AtcApplication) lastHeardOn(frequencies []voice.Frequency) time.Time {
+	a.lanesLock.Lock()
+	defer a.lanesLock.Unlock()
+	last := time.Time{}
+	for _, frequency := range frequencies {
+		if heard := a.lastHeard[laneName([]voice.Frequency{frequency})]; heard.After(last) {
+			last = heard
+		}
+	}
+	return last
+}
+
+// waitForQuiet holds the reply until its frequencies have been quiet for the gap. Safety and emergency calls
+// don't wait.
+func (a *AtcApplication) waitForQuiet(lane *frequencyLane, msg message.OutgoingMessage) {
+	if msg.Priority >= message.PrioritySafety {
+		return
+	}
+	gap := durationOrDefault(a.QuietGap, DEFAULT_QUIET_GAP)
+	deadline := time.Now().Add(durationOrDefault(a.MaxTransmitDelay, DEFAULT_MAX_TRANSMIT_DELAY))
+
+	for {
+		wait := time.Until(a.lastHeardOn(msg.Message.Frequencies).Add(gap))
+		if wait <= 0 {
+			return
+		}
+		if time.Until(deadline) <= 0 {
+			log.Warn().Msgf("%s still busy, transmitting to %s anyway", lane.name, msg.Message.ClientName)
+			return
+		}
+		log.Info().Msgf("holding reply to %s, %s is busy", msg.Message.ClientName, lane.name)
+		select {
+		case <-a.stopCtx.Done():
+			return
+		case <-time.After(min(wait, time.Until(deadline))):
+		}
+	}
+}
+
+func durationOrDefault(duration time.Duration, defaultDuration time.Duration) time.Duration {
+	if duration <= 0 {
+		return defaultDuration
+	}
+	return duration
+}
@@ -0,0 +1,245 @@
+package atcclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	deepgramspeaker "github.com/ErikGoldman/DCSAtcOverhaul/pkg/deepgramSpeaker"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/simpleradio"
+	"github.com/dharmab/skyeye/pkg/simpleradio/voice"
+	"github.com/rs/zerolog/log"
+)
+
+// Each frequency gets a lane, so a slow recognition or synthesis on one frequency doesn't hold up the others.
+// The slow work runs on worker pools shared by every lane, which start a frequency's jobs as soon as there's a
+// free worker, and the lane hands transcripts on in the order they were transmitted. Parsed commands all go
+// through the model loop, which applies them one at a time, so the model sees each frequency's calls in order.
+// Replies wait in the lane's queue and go out one at a time, most important first.
+
+const (
+	DEFAULT_RECOGNITION_WORKERS = 4
+	DEFAULT_PARSING_WORKERS     = 4
+	DEFAULT_SYNTHESIS_WORKERS   = 2
+	// transmissions or replies a frequency can have waiting before we drop some
+	FREQUENCY_QUEUE_SIZE = 8
+	// give up on speech that hasn't finished by then
+	SYNTHESIS_TIMEOUT = 30 * time.Second
+)
+
+// pending is the result of a job on a worker pool that may not have finished yet
+type pending[T any] struct {
+	done  chan struct{}
+	value T
+	ok    bool
+}
+
+func newPending[T any]() *pending[T] {
+	return &pending[T]{done: make(chan struct{})}
+}
+
+func (p *pending[T]) resolve(value T, ok bool) {
+	p.value, p.ok = value, ok
+	close(p.done)
+}
+
+// wait blocks until the job is done, ok is false if it failed or we're stopping
+func (p *pending[T]) wait(ctx context.Context) (T, bool) {
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, false
+	case <-p.done:
+		return p.value, p.ok
+	}
+}
+
+// workerPool runs at most its size of jobs at once
+type workerPool struct {
+	slots chan struct{}
+}
+
+func newWorkerPool(size int) *workerPool {
+	return &workerPool{slots: make(chan struct{}, max(size, 1))}
+}
+
+// do runs the job once a worker is free, false if we stopped first
+func (p *workerPool) do(ctx context.Context, job func()) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case p.slots <- struct{}{}:
+	}
+	defer func() { <-p.slots }()
+	job()
+	return true
+}
+
+// frequencyLane keeps one frequency's transmissions and replies in order
+type frequencyLane struct {
+	name        string
+	transcripts chan *pending[message.Message[string]]
+	queue       *message.OutgoingQueue
+}
+
+// lanes are named after the first frequency, which SRS reports as the one the radio is tuned to
+func laneName(frequencies []voice.Frequency) string {
+	if len(frequencies) == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%.3f MHz", frequencies[0].Frequency/1e6)
+}
+
+// laneFor is the frequency's lane, started on first use
+func (a *AtcApplication) laneFor(frequencies []voice.Frequency) *frequencyLane {
+	name := laneName(frequencies)
+
+	a.lanesLock.Lock()
+	defer a.lanesLock.Unlock()
+	if lane, ok := a.lanes[name]; ok {
+		return lane
+	}
+	lane := &frequencyLane{
+		name:        name,
+		transcripts: make(chan *pending[message.Message[string]], FREQUENCY_QUEUE_SIZE),
+		queue:       message.NewOutgoingQueue(FREQUENCY_QUEUE_SIZE),
+	}
+	a.lanes[name] = lane
+	log.Info().Msgf("opened lane for %s", name)
+	go a.listen(lane)
+	go a.speak(lane)
+	return lane
+}
+
+func (a *AtcApplication) startPipeline() {
+	a.lanes = make(map[string]*frequencyLane)
+	a.lastHeard = make(map[string]time.Time)
+	a.recognizers = newWorkerPool(workersOrDefault(a.RecognitionWorkers, DEFAULT_RECOGNITION_WORKERS))
+	a.parsers = newWorkerPool(workersOrDefault(a.ParsingWorkers, DEFAULT_PARSING_WORKERS))
+
+	// a synthesizer only speaks one message at a time, so each synthesis worker needs its own
+	if a.NewSpeechSynthesizer == nil {
+		a.synthesizers = make(chan deepgramspeaker.TextToSpeech, 1)
+		a.synthesizers <- a.SpeechSynthesizer
+		return
+	}
+	workers := workersOrDefault(a.SynthesisWorkers, DEFAULT_SYNTHESIS_WORKERS)
+	a.synthesizers = make(chan deepgramspeaker.TextToSpeech, workers)
+	for range workers {
+		a.synthesizers <- a.NewSpeechSynthesizer()
+	}
+}
+
+func workersOrDefault(workers int, defaultWorkers int) int {
+	if workers <= 0 {
+		return defaultWorkers
+	}
+	return workers
+}
+
+// queueTransmission starts recognizing the transmission, the lane parses it when its turn comes
+func (a *AtcApplication) queueTransmission(lane *frequencyLane, transmission simpleradio.Transmission) {
+	transcript := newPending[message.Message[string]]()
+	select {
+	case lane.transcripts <- transcript:
+	default:
+		log.Error().Msgf("dropping transmission from %s, %s is backed up", transmission.ClientName, lane.name)
+		return
+	}
+
+	go func() {
+		var msg message.Message[string]
+		ok := false
+		a.recognizers.do(a.stopCtx, func() {
+			msg, ok = a.recognizeTransmission(a.stopCtx, nil, transmission)
+		})
+		transcript.resolve(msg, ok)
+	}()
+}
+
+// listen parses the lane's transcripts in the order they were transmitted
+func (a *AtcApplication) listen(lane *frequencyLane) {
+	for {
+		select {
+		case <-a.stopCtx.Done():
+			return
+		case transcript := <-lane.transcripts:
+			msg, ok := transcript.wait(a.stopCtx)
+			if !ok {
+				continue
+			}
+			a.parsers.do(a.stopCtx, func() {
+				a.processTranscript(msg)
+			})
+		}
+	}
+}
+
+// speak makes the lane's calls, most important first. Calls that go stale while they're synthesized or waiting
+// for the frequency to go quiet are dropped.
+func (a *AtcApplication) speak(lane *frequencyLane) {
+	for {
+		msg, ok := lane.queue.Pop(a.stopCtx)
+		if !ok {
+			return
+		}
+
+		var audio [][]byte
+		select {
+		case <-a.stopCtx.Done():
+			return
+		case synthesizer := <-a.synthesizers:
+			audio, ok = a.synthesize(synthesizer, msg)
+			a.synthesizers <- synthesizer
+		}
+		if !ok {
+			continue
+		}
+
+		a.waitForQuiet(lane, msg)
+		if lane.queue.Stale(msg) {
+			log.Info().Msgf("dropping stale call to %s: %s", msg.Message.ClientName, msg.Message.Data)
+			continue
+		}
+		for _, audioBytes := range audio {
+			log.Info().Msgf("sending voice transmission on %s", lane.name)
+			a.radioClient.Transmit(simpleradio.Transmission{
+				TraceID:     msg.Message.TraceId,
+				ClientName:  msg.Message.ClientName,
+				Frequencies: msg.Message.Frequencies,
+				Audio:       convertLinear16ToFloat32(audioBytes),
+			})
+		}
+	}
+}
+
+// synthesize collects the whole reply, so we can check it's still worth making before it goes out
+func (a *AtcApplication) synthesize(synthesizer deepgramspeaker.TextToSpeech, msg message.OutgoingMessage) ([][]byte, bool) {
+	audioChannel := make(chan []byte, 5)
+
+	// use text to speech API and collect the results for the radio client
+	if err := synthesizer.GenerateSpeech(msg.Model, msg.Message.Data, audioChannel); err != nil {
+		log.Error().Err(err).Msg("error generating speech")
+		return nil, false
+	}
+	defer synthesizer.Disconnect()
+
+	audio := [][]byte{}
+	timeout := time.After(SYNTHESIS_TIMEOUT)
+	for {
+		select {
+		case <-a.stopCtx.Done():
+			return nil, false
+		case <-timeout:
+			log.Error().Msgf("timeout generating speech: %s", msg.Message.Data)
+			return nil, false
+		case audioBytes := <-audioChannel:
+			if audioBytes == nil {
+				log.Info().Msg("got end of TTS stream")
+				return audio, true
+			}
+			audio = append(audio, audioBytes)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package atcclienttest
+
+import (
+	"testing"
+	"time"
+
+	atcclient "github.com/ErikGoldman/DCSAtcOverhaul/pkg/client"
+	atcclienttesthelpers "github.com/ErikGoldman/DCSAtcOverhaul/pkg/client/testhelpers"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/simpleradio"
+	"github.com/dharmab/skyeye/pkg/simpleradio/voice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestClearChannel_HoldsReplies(t *testing.T) {
+	tests := []struct {
+		name     string
+		quietGap time.Duration
+		maxDelay time.Duration
+		priority message.Priority
+		// how long after the pilot's transmission the reply should go out
+		atLeast time.Duration
+		before  time.Duration
+	}{
+		{"waits for the gap", 300 * time.Millisecond, 5 * time.Second, message.PriorityClearance, 300 * time.Millisecond, 2 * time.Second},
+		{"safety calls don't wait", 2 * time.Second, 5 * time.Second, message.PrioritySafety, 0, time.Second},
+		{"gives up after the max delay", 5 * time.Second, 300 * time.Millisecond, message.PriorityClearance, 300 * time.Millisecond, 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &atcclienttesthelpers.MockSRSClient{}
+			mockRecognizer := &atcclienttesthelpers.MockRecognizer{}
+			mockTextToSpeech := &MockTextToSpeech{}
+
+			app := &atcclient.AtcApplication{
+				Recognizer:        mockRecognizer,
+				SpeechSynthesizer: mockTextToSpeech,
+				CommandProcessor:  atcclienttesthelpers.NewMockCommandProcessor(),
+				QuietGap:          tt.quietGap,
+				MaxTransmitDelay:  tt.maxDelay,
+			}
+
+			transmissionChan := make(chan simpleradio.Transmission)
+			mockClient.On("Receive").Return(transmissionChan)
+			mockRecognizer.On("Recognize", mock.Anything, mock.Anything, mock.Anything).Return("", nil)
+			mockTextToSpeech.On("GenerateSpeech", mock.Anything, mock.Anything, mock.Anything).Run(
+				func(args mock.Arguments) {
+					byteChan := args.Get(2).(chan []byte)
+					byteChan <- []byte{1, 2}
+					byteChan <- nil
+				},
+			).Return(nil)
+			mockTextToSpeech.On("Disconnect").Return(nil)
+
+			transmitted := make(chan time.Time, 1)
+			mockClient.On("Transmit", mock.Anything).Run(func(args mock.Arguments) {
+				transmitted <- time.Now()
+			})
+
+			go app.Start(mockClient)
+			heard := time.Now()
+			transmissionChan <- transmissionOn(251, "hawg31", 2)
+			app.Send(message.OutgoingMessage{
+				Message: message.Message[string]{
+					ClientName:  "hawg31",
+					Data:        "hawg31, go around",
+					Frequencies: []voice.Frequency{{Frequency: 251e6}},
+				},
+				Priority: tt.priority,
+			})
+
+			select {
+			case at := <-transmitted:
+				assert.GreaterOrEqual(t, at.Sub(heard), tt.atLeast)
+				assert.Less(t, at.Sub(heard), tt.before)
+			case <-time.After(tt.before):
+				assert.Fail(t, "Expected the reply to be transmitted")
+			}
+			app.Stop()
+		})
+	}
+}
+
+func TestClearChannel_OtherFrequenciesDontWait(t *testing.T) {
+	mockClient := &atcclienttesthelpers.MockSRSClient{}
+	mockRecognizer := &atcclienttesthelpers.MockRecognizer{}
+	mockTextToSpeech := &MockTextToSpeech{}
+
+	app := &atcclient.AtcApplication{
+		Recognizer:        mockRecognizer,
+		SpeechSynthesizer: mockTextToSpeech,
+		CommandProcessor:  atcclienttesthelpers.NewMockCommandProcessor(),
+		QuietGap:          5 * time.Second,
+	}
+
+	transmissionChan := make(chan simpleradio.Transmission)
+	mockClient.On("Receive").Return(transmissionChan)
+	mockRecognizer.On("Recognize", mock.Anything, mock.Anything, mock.Anything).Return("", nil)
+	mockTextToSpeech.On("GenerateSpeech", mock.Anything, mock.Anything, mock.Anything).Run(
+		func(args mock.Arguments) {
+			byteChan := args.Get(2).(chan []byte)
+			byteChan <- []byte{1, 2}
+			byteChan <- nil
+		},
+	).Return(nil)
+	mockTextToSpeech.On("Disconnect").Return(nil)
+
+	transmitted := make(chan string, 1)
+	mockClient.On("Transmit", mock.Anything).Run(func(args mock.Arguments) {
+		transmitted <- args.Get(0).(simpleradio.Transmission).ClientName
+	})
+
+	go app.Start(mockClient)
+	transmissionChan <- transmissionOn(251, "hawg31", 2)
+	app.Send(message.OutgoingMessage{
+		Message: message.Message[string]{
+			ClientName:  "dude11",
+			Data:        "dude11, loud and clear",
+			Frequencies: []voice.Frequency{{Frequency: 305e6}},
+		},
+	})
+
+	select {
+	case clientName := <-transmitted:
+		assert.Equal(t, "dude11", clientName)
+	case <-time.After(time.Second):
+		assert.Fail(t, "Expected the reply on a quiet frequency to go out straight away")
+	}
+	app.Stop()
+}
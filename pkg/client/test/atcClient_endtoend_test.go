@@ -3,6 +3,7 @@ package atcclienttest
 import (
 	"testing"
 
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
 	atcclient "github.com/ErikGoldman/DCSAtcOverhaul/pkg/client"
 	atcclienttesthelpers "github.com/ErikGoldman/DCSAtcOverhaul/pkg/client/testhelpers"
 	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/commands"
@@ -38,6 +39,8 @@ func TestClient_EndToEnd(t *testing.T) {
 		SpeechSynthesizer:          mockTextToSpeech,
 		CommandProcessor:           commandProcessor,
 		EnableTranscriptionLogging: true,
+		// commands are carried out by the model
+		AtcModel: atcmodel.NewAtcModel(atcmodel.AtcMap{}),
 	}
 
 	// EXPECTATIONS
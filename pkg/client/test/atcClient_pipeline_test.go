@@ -0,0 +1,259 @@
+package atcclienttest
+
+import (
+	"testing"
+	"time"
+
+	atcclient "github.com/ErikGoldman/DCSAtcOverhaul/pkg/client"
+	atcclienttesthelpers "github.com/ErikGoldman/DCSAtcOverhaul/pkg/client/testhelpers"
+	deepgramspeaker "github.com/ErikGoldman/DCSAtcOverhaul/pkg/deepgramSpeaker"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/simpleradio"
+	"github.com/dharmab/skyeye/pkg/simpleradio/voice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func transmissionOn(mhz float64, clientName string, audio float32) simpleradio.Transmission {
+	return simpleradio.Transmission{
+		Frequencies: []voice.Frequency{{Frequency: mhz * 1e6, Modulation: 0}},
+		ClientName:  clientName,
+		Audio:       []float32{audio},
+	}
+}
+
+// slowRecognizer holds up recognition of audio starting with 1 until released
+func slowRecognizer(release chan struct{}, started chan string) *atcclienttesthelpers.MockRecognizer {
+	mockRecognizer := &atcclienttesthelpers.MockRecognizer{}
+	mockRecognizer.On("Recognize", mock.Anything, mock.MatchedBy(func(pcm []float32) bool { return pcm[0] == 1 }),
+		mock.Anything).Run(func(args mock.Arguments) {
+		started <- "slow"
+		<-release
+	}).Return("slow", nil)
+	mockRecognizer.On("Recognize", mock.Anything, mock.MatchedBy(func(pcm []float32) bool { return pcm[0] == 2 }),
+		mock.Anything).Run(func(args mock.Arguments) {
+		started <- "fast"
+	}).Return("fast", nil)
+	return mockRecognizer
+}
+
+func nextTranscript(t *testing.T, transcripts chan message.Message[string]) string {
+	select {
+	case msg := <-transcripts:
+		return msg.Data
+	case <-time.After(time.Second):
+		assert.Fail(t, "Expected a transcript to be processed, but got none")
+		return ""
+	}
+}
+
+func TestPipeline_SlowFrequencyDoesNotBlockOthers(t *testing.T) {
+	mockClient := &atcclienttesthelpers.MockSRSClient{}
+	mockProcessor := atcclienttesthelpers.NewMockCommandProcessor()
+	release, started := make(chan struct{}), make(chan string, 2)
+
+	app := &atcclient.AtcApplication{
+		Recognizer:       slowRecognizer(release, started),
+		CommandProcessor: mockProcessor,
+	}
+
+	transmissionChan := make(chan simpleradio.Transmission)
+	mockClient.On("Receive").Return(transmissionChan)
+
+	go app.Start(mockClient)
+	transmissionChan <- transmissionOn(251, "slow", 1)
+	transmissionChan <- transmissionOn(305, "fast", 2)
+
+	assert.Equal(t, "fast", nextTranscript(t, mockProcessor.Transcripts))
+	close(release)
+	assert.Equal(t, "slow", nextTranscript(t, mockProcessor.Transcripts))
+	app.Stop()
+}
+
+func TestPipeline_FrequencyKeepsOrder(t *testing.T) {
+	mockClient := &atcclienttesthelpers.MockSRSClient{}
+	mockProcessor := atcclienttesthelpers.NewMockCommandProcessor()
+	release, started := make(chan struct{}), make(chan string, 2)
+
+	app := &atcclient.AtcApplication{
+		Recognizer:       slowRecognizer(release, started),
+		CommandProcessor: mockProcessor,
+	}
+
+	transmissionChan := make(chan simpleradio.Transmission)
+	mockClient.On("Receive").Return(transmissionChan)
+
+	go app.Start(mockClient)
+	transmissionChan <- transmissionOn(251, "first", 1)
+	transmissionChan <- transmissionOn(251, "second", 2)
+
+	// both are recognized at once, but the second waits its turn to be parsed
+	assert.ElementsMatch(t, []string{"slow", "fast"}, []string{<-started, <-started})
+	select {
+	case msg := <-mockProcessor.Transcripts:
+		assert.Fail(t, "Expected the second transcript to wait for the first, but got %s", msg.Data)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	assert.Equal(t, "slow", nextTranscript(t, mockProcessor.Transcripts))
+	assert.Equal(t, "fast", nextTranscript(t, mockProcessor.Transcripts))
+	app.Stop()
+}
+
+func TestPipeline_RepliesKeepOrder(t *testing.T) {
+	mockClient := &atcclienttesthelpers.MockSRSClient{}
+	mockRecognizer := &atcclienttesthelpers.MockRecognizer{}
+	mockTextToSpeech := &MockTextToSpeech{}
+	release := make(chan struct{})
+
+	app := &atcclient.AtcApplication{
+		Recognizer:           mockRecognizer,
+		CommandProcessor:     atcclienttesthelpers.NewMockCommandProcessor(),
+		NewSpeechSynthesizer: func() deepgramspeaker.TextToSpeech { return mockTextToSpeech },
+		SynthesisWorkers:     2,
+	}
+
+	transmissionChan := make(chan simpleradio.Transmission)
+	mockClient.On("Receive").Return(transmissionChan)
+	mockRecognizer.On("Recognize", mock.Anything, mock.Anything, mock.Anything).Return("", nil)
+
+	// the first reply takes a while to synthesize
+	mockTextToSpeech.On("GenerateSpeech", mock.Anything, "cleared for takeoff", mock.Anything).Run(
+		func(args mock.Arguments) {
+			byteChan := args.Get(2).(chan []byte)
+			go func() {
+				<-release
+				byteChan <- []byte{1, 2}
+				byteChan <- nil
+			}()
+		},
+	).Return(nil)
+	mockTextToSpeech.On("GenerateSpeech", mock.Anything, mock.Anything, mock.Anything).Run(
+		func(args mock.Arguments) {
+			byteChan := args.Get(2).(chan []byte)
+			byteChan <- []byte{3, 4}
+			byteChan <- nil
+		},
+	).Return(nil)
+	mockTextToSpeech.On("Disconnect").Return(nil)
+
+	transmitted := make(chan string, 3)
+	mockClient.On("Transmit", mock.Anything).Run(func(args mock.Arguments) {
+		transmitted <- args.Get(0).(simpleradio.Transmission).ClientName
+	})
+
+	go app.Start(mockClient)
+	// once the loop has the transmission the application is running
+	transmissionChan <- transmissionOn(124, "player", 2)
+
+	reply := func(mhz float64, clientName string, text string) message.OutgoingMessage {
+		return message.OutgoingMessage{Message: message.Message[string]{
+			ClientName:  clientName,
+			Data:        text,
+			Frequencies: []voice.Frequency{{Frequency: mhz * 1e6}},
+		}}
+	}
+	app.Send(reply(251, "first", "cleared for takeoff"))
+	app.Send(reply(251, "second", "loud and clear"))
+	app.Send(reply(305, "other", "loud and clear"))
+
+	next := func() string {
+		select {
+		case clientName := <-transmitted:
+			return clientName
+		case <-time.After(time.Second):
+			assert.Fail(t, "Expected a transmission, but got none")
+			return ""
+		}
+	}
+	// the other frequency doesn't wait, the second reply on 251 does
+	assert.Equal(t, "other", next())
+	select {
+	case clientName := <-transmitted:
+		assert.Fail(t, "Expected the second reply to wait for the first, but got %s", clientName)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	assert.Equal(t, "first", next())
+	assert.Equal(t, "second", next())
+	app.Stop()
+}
+
+func TestPipeline_RepliesByPriority(t *testing.T) {
+	mockClient := &atcclienttesthelpers.MockSRSClient{}
+	mockRecognizer := &atcclienttesthelpers.MockRecognizer{}
+	mockTextToSpeech := &MockTextToSpeech{}
+	release := make(chan struct{})
+
+	app := &atcclient.AtcApplication{
+		Recognizer:        mockRecognizer,
+		CommandProcessor:  atcclienttesthelpers.NewMockCommandProcessor(),
+		SpeechSynthesizer: mockTextToSpeech,
+	}
+
+	transmissionChan := make(chan simpleradio.Transmission)
+	mockClient.On("Receive").Return(transmissionChan)
+	mockRecognizer.On("Recognize", mock.Anything, mock.Anything, mock.Anything).Return("", nil)
+
+	// holds the frequency while the rest queue up behind it
+	mockTextToSpeech.On("GenerateSpeech", mock.Anything, "radio check, loud and clear", mock.Anything).Run(
+		func(args mock.Arguments) {
+			byteChan := args.Get(2).(chan []byte)
+			go func() {
+				<-release
+				byteChan <- []byte{1, 2}
+				byteChan <- nil
+			}()
+		},
+	).Return(nil)
+	mockTextToSpeech.On("GenerateSpeech", mock.Anything, mock.Anything, mock.Anything).Run(
+		func(args mock.Arguments) {
+			byteChan := args.Get(2).(chan []byte)
+			byteChan <- []byte{3, 4}
+			byteChan <- nil
+		},
+	).Return(nil)
+	mockTextToSpeech.On("Disconnect").Return(nil)
+
+	transmitted := make(chan string, 5)
+	mockClient.On("Transmit", mock.Anything).Run(func(args mock.Arguments) {
+		transmitted <- args.Get(0).(simpleradio.Transmission).ClientName
+	})
+
+	go app.Start(mockClient)
+	transmissionChan <- transmissionOn(124, "player", 2)
+
+	call := func(clientName string, text string, priority message.Priority, key string) message.OutgoingMessage {
+		return message.OutgoingMessage{
+			Message: message.Message[string]{
+				ClientName:  clientName,
+				Data:        text,
+				Frequencies: []voice.Frequency{{Frequency: 251e6}},
+			},
+			Priority: priority,
+			Key:      key,
+		}
+	}
+	app.Send(call("dude11", "radio check, loud and clear", message.PriorityClearance, ""))
+	time.Sleep(50 * time.Millisecond)
+	app.Send(call("anapa ATIS", "anapa information alpha", message.PriorityInformational, "atis-anapa"))
+	app.Send(call("hawg31", "hawg31, cleared to land", message.PriorityClearance, "arrival-1"))
+	app.Send(call("hawg31", "hawg31, go around", message.PrioritySafety, "arrival-1"))
+	close(release)
+
+	received := []string{}
+	for range 3 {
+		select {
+		case clientName := <-transmitted:
+			received = append(received, clientName)
+		case <-time.After(time.Second):
+			assert.Fail(t, "Expected a transmission, but got none")
+		}
+	}
+	// the landing clearance was replaced by the go around, which jumps the ATIS
+	assert.Equal(t, []string{"dude11", "hawg31", "anapa ATIS"}, received)
+	mockTextToSpeech.AssertNotCalled(t, "GenerateSpeech", mock.Anything, "hawg31, cleared to land", mock.Anything)
+	app.Stop()
+}
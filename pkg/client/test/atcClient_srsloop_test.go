@@ -2,6 +2,7 @@ package atcclienttest
 
 import (
 	"testing"
+	"time"
 
 	atcclient "github.com/ErikGoldman/DCSAtcOverhaul/pkg/client"
 	atcclienttesthelpers "github.com/ErikGoldman/DCSAtcOverhaul/pkg/client/testhelpers"
@@ -15,9 +16,11 @@ import (
 func TestSRSLoop_SimpleSingleFrequency(t *testing.T) {
 	mockClient := &atcclienttesthelpers.MockSRSClient{}
 	mockRecognizer := &atcclienttesthelpers.MockRecognizer{}
+	mockProcessor := atcclienttesthelpers.NewMockCommandProcessor()
 
 	app := &atcclient.AtcApplication{
 		Recognizer:                 mockRecognizer,
+		CommandProcessor:           mockProcessor,
 		EnableTranscriptionLogging: false,
 	}
 
@@ -42,9 +45,17 @@ func TestSRSLoop_SimpleSingleFrequency(t *testing.T) {
 		ClientName: "MyClientName",
 		Audio:      []float32{1, 2, 3, 4, 10},
 	}
-	app.Stop()
 
 	// ASSERTIONS
+	var msg message.Message[string]
+	select {
+	case msg = <-mockProcessor.Transcripts:
+	case <-time.After(time.Second):
+		assert.Fail(t, "Expected one transcript to be processed, but got none")
+		return
+	}
+	app.Stop()
+
 	mockClient.AssertExpectations(t)
 
 	mockRecognizer.AssertNumberOfCalls(t, "Recognize", 1)
@@ -59,14 +70,6 @@ func TestSRSLoop_SimpleSingleFrequency(t *testing.T) {
 		}),
 	)
 
-	var msg message.Message[string]
-	select {
-	case msg = <-app.TranscribedMessages:
-	default:
-		assert.Fail(t, "Expected one message on TranscribedMessages, but got none")
-		return
-	}
-
 	assert.EqualValues(t, msg.Data, "recognized text")
 	assert.Len(t, msg.Frequencies, 1)
 	assert.EqualValues(t, msg.Frequencies[0].Frequency, 123.4)
@@ -76,8 +79,8 @@ func TestSRSLoop_SimpleSingleFrequency(t *testing.T) {
 	assert.EqualValues(t, msg.TraceId, "MyTraceId")
 
 	select {
-	case msgTwo := <-app.TranscribedMessages:
-		assert.Fail(t, "Expected one message on TranscribedMessages, but got %s", msgTwo.Data)
+	case msgTwo := <-mockProcessor.Transcripts:
+		assert.Fail(t, "Expected one transcript to be processed, but got %s", msgTwo.Data)
 		return
 	default:
 		// No additional messages, which is expected
@@ -87,9 +90,11 @@ func TestSRSLoop_SimpleSingleFrequency(t *testing.T) {
 func TestSRSLoop_SimpleMultiFrequency(t *testing.T) {
 	mockClient := &atcclienttesthelpers.MockSRSClient{}
 	mockRecognizer := &atcclienttesthelpers.MockRecognizer{}
+	mockProcessor := atcclienttesthelpers.NewMockCommandProcessor()
 
-	app := &AtcApplication{
+	app := &atcclient.AtcApplication{
 		Recognizer:                 mockRecognizer,
+		CommandProcessor:           mockProcessor,
 		EnableTranscriptionLogging: false,
 	}
 
@@ -119,9 +124,17 @@ func TestSRSLoop_SimpleMultiFrequency(t *testing.T) {
 		ClientName: "MyClientName",
 		Audio:      []float32{1, 2, 3, 4, 10},
 	}
-	app.Stop()
 
 	// ASSERTIONS
+	var msg message.Message[string]
+	select {
+	case msg = <-mockProcessor.Transcripts:
+	case <-time.After(time.Second):
+		assert.Fail(t, "Expected one transcript to be processed, but got none")
+		return
+	}
+	app.Stop()
+
 	mockClient.AssertExpectations(t)
 
 	mockRecognizer.AssertNumberOfCalls(t, "Recognize", 1)
@@ -131,21 +144,13 @@ func TestSRSLoop_SimpleMultiFrequency(t *testing.T) {
 		mock.Anything, // context
 	)
 
-	var msg message.Message[string]
-	select {
-	case msg = <-app.TranscribedMessages:
-	default:
-		assert.Fail(t, "Expected one message on TranscribedMessages, but got none")
-		return
-	}
-
 	assert.Len(t, msg.Frequencies, 2)
 	assert.EqualValues(t, msg.Frequencies[0].Frequency, 245.8)
 	assert.EqualValues(t, msg.Frequencies[1].Frequency, 123.4)
 
 	select {
-	case msgTwo := <-app.TranscribedMessages:
-		assert.Fail(t, "Expected one message on TranscribedMessages, but got %s", msgTwo.Data)
+	case msgTwo := <-mockProcessor.Transcripts:
+		assert.Fail(t, "Expected one transcript to be processed, but got %s", msgTwo.Data)
 		return
 	default:
 		// No additional messages, which is expected
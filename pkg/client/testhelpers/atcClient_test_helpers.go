@@ -4,6 +4,8 @@ import (
 	"context"
 	"sync"
 
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/commands"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
 	"github.com/dharmab/skyeye/pkg/simpleradio"
 	"github.com/dharmab/skyeye/pkg/simpleradio/types"
 	"github.com/stretchr/testify/mock"
@@ -66,6 +68,20 @@ type MockRecognizer struct {
 	mock.Mock
 }
 
+// MockCommandProcessor hands every transcript it's given to Transcripts, and never finds a command
+type MockCommandProcessor struct {
+	Transcripts chan message.Message[string]
+}
+
+func NewMockCommandProcessor() *MockCommandProcessor {
+	return &MockCommandProcessor{Transcripts: make(chan message.Message[string], 10)}
+}
+
+func (m *MockCommandProcessor) ProcessText(ctx context.Context, msg *message.Message[string]) (commands.PlayerCommandMessage, error) {
+	m.Transcripts <- *msg
+	return commands.PlayerCommandMessage{Message: msg}, nil
+}
+
 func IsAudioEqual(a []float32, b []float32) bool {
 	if len(a) != len(b) {
 		return false
@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+)
+
+// DCS flight callsigns, e.g. "Uzi 2-1" is flight two, first aircraft
+
+// spoken digits, including the radiotelephony pronunciations
+var spokenDigits = map[string]int{
+	"zero": 0, "one": 1, "two": 2, "three": 3, "tree": 3, "four": 4, "five": 5, "fife": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "niner": 9,
+}
+
+// words speech recognition writes instead of a digit. Only taken as a digit when another digit follows, so
+// "colt 1 taxi to runway" stays colt 1
+var digitHomophones = map[string]int{"to": 2, "too": 2, "won": 1, "for": 4, "ate": 8}
+
+// how speech recognition tends to spell callsign names
+var callsignAliases = map[string]string{
+	"hog":     "hawg",
+	"hogg":    "hawg",
+	"hawgs":   "hawg",
+	"enfeld":  "enfield",
+	"pontiak": "pontiac",
+}
+
+// the flight callsigns DCS hands out, so chatter like "push button two" isn't mistaken for someone calling us
+var knownCallsigns = map[string]bool{
+	"enfield": true, "springfield": true, "uzi": true, "colt": true, "dodge": true, "ford": true, "chevy": true,
+	"pontiac": true, "hawg": true, "boar": true, "pig": true, "tusk": true, "viper": true, "venom": true,
+	"lobo": true, "cowboy": true, "python": true, "rattler": true, "panther": true, "wolf": true, "weasel": true,
+	"wild": true, "ninja": true, "jedi": true, "hornet": true, "squid": true, "ragin": true, "roman": true,
+	"sting": true, "jury": true, "joker": true, "ram": true, "hawk": true, "devil": true, "check": true,
+	"snake": true, "dude": true, "thud": true, "gunny": true, "trek": true, "sniper": true, "sled": true,
+	"best": true, "jazz": true, "rage": true, "tahoe": true, "bone": true, "dark": true, "vader": true,
+	"buff": true, "dump": true, "kenworth": true, "texaco": true, "arco": true, "shell": true, "overlord": true,
+	"magic": true, "wizard": true, "focus": true, "darkstar": true,
+}
+
+// words that come before numbers in ATC calls but are never a callsign
+var notCallsigns = map[string]bool{
+	"tower": true, "ground": true, "approach": true, "runway": true, "parking": true, "spot": true, "spots": true,
+	"position": true, "positions": true, "stand": true, "of": true, "and": true, "at": true, "flight": true,
+	"heading": true, "angels": true, "altitude": true, "information": true, "gate": true, "ramp": true,
+	"for": true, "to": true, "with": true, "request": true, "requesting": true, "miles": true, "mile": true,
+	"number": true, "squawk": true, "via": true, "taxiway": true, "short": true,
+}
+
+type Callsign struct {
+	// lower case, e.g. "hawg"
+	Name   string
+	Flight int
+	// position in the flight, 0 if the pilot only gave the flight number
+	Element int
+}
+
+// String is how the controller says it back, e.g. "hawg 3 1"
+func (c Callsign) String() string {
+	if c.Element == 0 {
+		return fmt.Sprintf("%s %d", c.Name, c.Flight)
+	}
+	return fmt.Sprintf("%s %d %d", c.Name, c.Flight, c.Element)
+}
+
+// Known is true for the flight callsigns DCS uses
+func (c Callsign) Known() bool {
+	return knownCallsigns[c.Name]
+}
+
+// Key is used to match callsigns however they were written, e.g. "Hawg 3-1", "hawg31" and "hawg three one" are
+// all "hawg31"
+func (c Callsign) Key() string {
+	if c.Element == 0 {
+		return fmt.Sprintf("%s%d", c.Name, c.Flight)
+	}
+	return fmt.Sprintf("%s%d%d", c.Name, c.Flight, c.Element)
+}
+
+// ParseCallsign finds the first callsign in a transcript or unit name
+func ParseCallsign(text string) (Callsign, bool) {
+	words := callsignWords(text)
+	for i, word := range words {
+		name, digits, ok := splitCallsignWord(word)
+		if !ok {
+			continue
+		}
+		for j := i + 1; j < len(words) && len(digits) < 2; j++ {
+			more, ok := wordDigits(words[j])
+			if homophone, isHomophone := digitHomophones[words[j]]; isHomophone && j+1 < len(words) {
+				_, ok = wordDigits(words[j+1])
+				more = []int{homophone}
+			}
+			if !ok || len(digits)+len(more) > 2 {
+				break
+			}
+			digits = append(digits, more...)
+		}
+		if len(digits) == 0 || digits[0] == 0 || (len(digits) > 1 && digits[1] == 0) {
+			continue
+		}
+
+		callsign := Callsign{Name: name, Flight: digits[0]}
+		if len(digits) > 1 {
+			callsign.Element = digits[1]
+		}
+		return callsign, true
+	}
+	return Callsign{}, false
+}
+
+func callsignWords(text string) []string {
+	text = strings.ToLower(text)
+	text = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return ' '
+	}, text)
+	return strings.Fields(text)
+}
+
+// splitCallsignWord accepts a callsign name, possibly with the numbers run on, e.g. "hawg" or "hawg31"
+func splitCallsignWord(word string) (string, []int, bool) {
+	name := strings.TrimRight(word, "0123456789")
+	// also skips aircraft types like "a-10" and "f-16"
+	if len(name) < 3 || notCallsigns[name] {
+		return "", nil, false
+	}
+	if _, ok := spokenDigits[name]; ok {
+		return "", nil, false
+	}
+
+	digits, _ := wordDigits(word[len(name):])
+	if len(digits) > 2 {
+		return "", nil, false
+	}
+	if alias, ok := callsignAliases[name]; ok {
+		name = alias
+	}
+	return name, digits, true
+}
+
+// wordDigits reads "3", "31" or "three" as digits
+func wordDigits(word string) ([]int, bool) {
+	if digit, ok := spokenDigits[word]; ok {
+		return []int{digit}, true
+	}
+	if word == "" {
+		return nil, true
+	}
+	digits := []int{}
+	for _, r := range word {
+		digit, err := strconv.Atoi(string(r))
+		if err != nil {
+			return nil, false
+		}
+		digits = append(digits, digit)
+	}
+	return digits, true
+}
+
+// resolveCaller works out who is calling: the callsign they said if it's a plane we're tracking, otherwise whatever
+// plane the model has for their SRS client. Returns how to address them and their plane, if we can find it.
+func resolveCaller(atc *atcmodel.AtcModel, msg *message.Message[string]) (string, uint64, bool) {
+	callsign, spoken := ParseCallsign(msg.Data)
+	addressAs := msg.ClientName
+	if spoken {
+		addressAs = callsign.String()
+	}
+	if atc == nil {
+		return addressAs, 0, false
+	}
+
+	if spoken {
+		for name, id := range atc.CallsignToId {
+			if unitCallsign, ok := ParseCallsign(name); ok && unitCallsign.Key() == callsign.Key() {
+				// so we still know who they are when they leave the callsign off
+				atc.LinkSpeaker(msg.ClientName, *id)
+				return addressAs, *id, true
+			}
+		}
+	}
+
+	planeId, found := atc.ResolveSpeaker(msg.ClientName)
+	return addressAs, planeId, found
+}
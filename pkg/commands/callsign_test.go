@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/trackfiles"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCallsign(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Callsign
+		found    bool
+	}{
+		{input: "anapa tower, uzi two-one, radio check", expected: Callsign{Name: "uzi", Flight: 2, Element: 1}, found: true},
+		{input: "Anapa tower, Hawg 3-1, runway 04, ready for takeoff", expected: Callsign{Name: "hawg", Flight: 3, Element: 1}, found: true},
+		{input: "kobuleti ground enfield one one ready to taxi", expected: Callsign{Name: "enfield", Flight: 1, Element: 1}, found: true},
+		{input: "anapa tower hawg 3 1 15 miles west inbound for landing", expected: Callsign{Name: "hawg", Flight: 3, Element: 1}, found: true},
+		{input: "tower, hog tree one, request taxi", expected: Callsign{Name: "hawg", Flight: 3, Element: 1}, found: true},
+		{input: "tower viper 1 1 4-ship f-16 parking spots 12, 13", expected: Callsign{Name: "viper", Flight: 1, Element: 1}, found: true},
+		{input: "colt niner request startup", expected: Callsign{Name: "colt", Flight: 9}, found: true},
+		{input: "anapa tower uzi to one radio check", expected: Callsign{Name: "uzi", Flight: 2, Element: 1}, found: true},
+		{input: "ground colt 1 request taxi to runway 22", expected: Callsign{Name: "colt", Flight: 1}, found: true},
+		{input: "Hawg31", expected: Callsign{Name: "hawg", Flight: 3, Element: 1}, found: true},
+		{input: "Aerial-1-1", expected: Callsign{Name: "aerial", Flight: 1, Element: 1}, found: true},
+		{input: "tower flight of two a-10s at parking 86 request startup", found: false},
+		{input: "anapa ground request taxi runway 04", found: false},
+		{input: "Goldylox", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			callsign, found := ParseCallsign(tt.input)
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.expected, callsign)
+		})
+	}
+
+	assert.Equal(t, "hawg 3 1", Callsign{Name: "hawg", Flight: 3, Element: 1}.String())
+	assert.Equal(t, "hawg31", Callsign{Name: "hawg", Flight: 3, Element: 1}.Key())
+}
+
+func TestResolveCaller(t *testing.T) {
+	model := atcmodel.NewAtcModel(atcmodel.AtcMap{})
+	addPlane(model, 1, "Hawg 3-1", trackfiles.Frame{})
+	addPlane(model, 2, "Goldylox", trackfiles.Frame{})
+
+	msg := func(clientName string, text string) *message.Message[string] {
+		return &message.Message[string]{Context: context.Background(), ClientName: clientName, Data: text}
+	}
+
+	// the SRS name doesn't match anything but the spoken callsign does
+	callsign, planeId, found := resolveCaller(model, msg("Bob", "anapa ground hawg three one request taxi"))
+	assert.Equal(t, "hawg 3 1", callsign)
+	assert.Equal(t, uint64(1), planeId)
+	assert.True(t, found)
+
+	// the player's unit is named after them, so fall back to the SRS name
+	callsign, planeId, found = resolveCaller(model, msg("Goldylox", "anapa ground colt one one request taxi"))
+	assert.Equal(t, "colt 1 1", callsign)
+	assert.Equal(t, uint64(2), planeId)
+	assert.True(t, found)
+
+	callsign, _, found = resolveCaller(model, msg("Goldylox", "anapa ground request taxi"))
+	assert.Equal(t, "Goldylox", callsign)
+	assert.True(t, found)
+
+	// having given their callsign once, they're still found without it
+	callsign, planeId, found = resolveCaller(model, msg("Bob", "anapa ground ready for departure"))
+	assert.Equal(t, "Bob", callsign)
+	assert.Equal(t, uint64(1), planeId)
+	assert.True(t, found)
+
+	_, _, found = resolveCaller(model, msg("Alice", "anapa ground request taxi"))
+	assert.False(t, found)
+}
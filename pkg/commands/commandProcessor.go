@@ -2,14 +2,29 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
 	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/martinlindhe/unit"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	// below this we ask the pilot to say again rather than guess
+	MIN_CONFIDENCE = 0.6
+	// commands scoring within this of each other are too close to call, so we ask which one the pilot meant
+	AMBIGUITY_MARGIN = 0.05
+)
+
+// ErrNotUnderstood comes back from ProcessText along with a command asking the pilot to say again
+var ErrNotUnderstood = errors.New("transmission not understood")
+
 type PlayerCommand interface {
 	Execute(atc *atcmodel.AtcModel, messageOut chan message.OutgoingMessage) error
 }
@@ -17,16 +32,32 @@ type PlayerCommand interface {
 // PlayerCommand represents a parsed command
 type PlayerCommandMessage struct {
 	Message       *message.Message[string]
+	Call          RadioCall
 	ParsedCommand PlayerCommand
 }
 
+// NoRequest is a parser deciding the call isn't asking for anything, e.g. a readback. If it's confident, no
+// command is run, whatever other parsers matched.
+type NoRequest struct{}
+
+func (c *NoRequest) String() string {
+	return "NoRequestCommand"
+}
+
+func (c *NoRequest) Execute(atc *atcmodel.AtcModel, messageOut chan message.OutgoingMessage) error {
+	return nil
+}
+
 type GlobalCommandContext struct {
 	rand Random
 }
 
-// PlayerCommandParser defines the interface for command parsers
+// PlayerCommandParser defines the interface for command parsers. Parsers decide from the call's intent and
+// slots, the message is for the reply. They return nil if the call isn't for them, otherwise the command and
+// how confident they are in it, from 0 to 1. Calls on different frequencies are parsed at the same time, so
+// parsers mustn't keep state between calls without locking it.
 type PlayerCommandParser interface {
-	Parse(globalContext *GlobalCommandContext, atcModel * ,message *message.Message[string]) PlayerCommand
+	Parse(globalContext *GlobalCommandContext, message *message.Message[string], call *RadioCall) (PlayerCommand, float64)
 }
 
 type CommandProcessorInterface interface {
@@ -37,6 +68,8 @@ type CommandProcessorInterface interface {
 type CommandProcessor struct {
 	parsers       []PlayerCommandParser
 	globalContext *GlobalCommandContext
+	// airfields and their frequencies, to send calls to the right controller. Nil to answer everything.
+	atcMap *atcmodel.AtcMap
 }
 
 func NewCommandProcessor(rand Random) *CommandProcessor {
@@ -48,25 +81,175 @@ func NewCommandProcessor(rand Random) *CommandProcessor {
 	}
 }
 
+// SetMap turns on facility routing, so calls made to the wrong controller are sent to the right one. Only the
+// airfield names and frequencies are read, which don't change once the map is loaded.
+func (cp *CommandProcessor) SetMap(atcMap *atcmodel.AtcMap) {
+	cp.atcMap = atcMap
+}
+
 // RegisterParser adds a new parser to the processor
 func (cp *CommandProcessor) RegisterParser(parser PlayerCommandParser) {
 	cp.parsers = append(cp.parsers, parser)
 }
 
-// ProcessCommand attempts to parse the input string using registered parsers
+type scoredCommand struct {
+	command    PlayerCommand
+	confidence float64
+}
+
+// ProcessText runs every registered parser over the transcript and picks the command they're most confident
+// in. If none is confident enough, or two are too close to call, the error wraps ErrNotUnderstood and the
+// command asks the pilot to say again. Readbacks, and chatter that wasn't for us, get no command.
 func (cp *CommandProcessor) ProcessText(ctx context.Context, message *message.Message[string]) (PlayerCommandMessage, error) {
 	message.Data = strings.ToLower(message.Data)
+	call := ParseRadioCall(message.Data)
+	log.Info().Msgf("parsed %s call to %s %s", call.Intent, call.Addressee, call.Facility)
 
+	result := PlayerCommandMessage{Message: message, Call: call}
+	// a readback repeats the instruction, so it would match the request it answers
+	if call.Acknowledgement {
+		return result, fmt.Errorf("readback, nothing to answer: %s", message.Data)
+	}
+
+	candidates := []scoredCommand{}
+	noRequest := false
 	for _, parser := range cp.parsers {
-		if cmd := parser.Parse(cp.globalContext, message); cmd != nil {
-			log.Info().Msgf("Matched to command %s", cmd)
-			return PlayerCommandMessage{
-				Message:       message,
-				ParsedCommand: cmd,
-			}, nil
+		cmd, confidence := parser.Parse(cp.globalContext, message, &call)
+		if _, ok := cmd.(*NoRequest); ok {
+			noRequest = noRequest || confidence >= MIN_CONFIDENCE
+			continue
+		}
+		if cmd != nil {
+			log.Info().Msgf("Matched to command %s with confidence %.2f", cmd, confidence)
+			candidates = append(candidates, scoredCommand{command: cmd, confidence: confidence})
+		}
+	}
+	if noRequest {
+		return result, fmt.Errorf("not a request: %s", message.Data)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].confidence > candidates[j].confidence
+	})
+
+	// parsers agreeing on what the pilot wants isn't ambiguous, so compare against the best different request
+	var runnerUp *scoredCommand
+	for i := 1; i < len(candidates); i++ {
+		if intentOf(candidates[i].command) != intentOf(candidates[0].command) {
+			runnerUp = &candidates[i]
+			break
+		}
+	}
+
+	understood := len(candidates) > 0 && candidates[0].confidence >= MIN_CONFIDENCE &&
+		(runnerUp == nil || candidates[0].confidence-runnerUp.confidence >= AMBIGUITY_MARGIN)
+	switch {
+	case !understood && !call.Addressed():
+		// only ask for a repeat if someone was actually calling us
+		return result, fmt.Errorf("not addressed to ATC: %s", message.Data)
+	case len(candidates) == 0:
+		result.ParsedCommand = &SayAgain{Message: message, Call: call}
+		return result, fmt.Errorf("%w, no parser found for command: %s", ErrNotUnderstood, message.Data)
+	case candidates[0].confidence < MIN_CONFIDENCE:
+		result.ParsedCommand = &SayAgain{Message: message, Call: call}
+		return result, fmt.Errorf("%w, best guess %s only %.2f confident: %s", ErrNotUnderstood,
+			candidates[0].command, candidates[0].confidence, message.Data)
+	case runnerUp != nil && candidates[0].confidence-runnerUp.confidence < AMBIGUITY_MARGIN:
+		result.ParsedCommand = &SayAgain{Message: message, Call: call,
+			Choices: []Intent{intentOf(candidates[0].command), intentOf(runnerUp.command)}}
+		return result, fmt.Errorf("%w, can't choose between %s and %s: %s", ErrNotUnderstood,
+			candidates[0].command, runnerUp.command, message.Data)
+	}
+
+	result.ParsedCommand = cp.route(message, &call, candidates[0].command)
+	return result, nil
+}
+
+// commands that only some controllers handle
+type facilityCommand interface {
+	Facilities() []atcmodel.Facility
+}
+
+// route sends the pilot to another controller if whoever is listening on the frequency doesn't handle the
+// request, or the pilot called a different airfield. Calls on frequencies that aren't an airfield's are
+// answered as they are.
+func (cp *CommandProcessor) route(msg *message.Message[string], call *RadioCall, cmd PlayerCommand) PlayerCommand {
+	c, ok := cmd.(facilityCommand)
+	if !ok || len(c.Facilities()) == 0 || cp.atcMap == nil {
+		return cmd
+	}
+
+	for _, frequency := range msg.Frequencies {
+		calledOn := unit.Frequency(frequency.Frequency) * unit.Hertz
+		airfield, listening, ok := cp.atcMap.StationOn(calledOn)
+		if !ok {
+			continue
+		}
+
+		target := airfield
+		if call.Addressee != "" {
+			if addressed := cp.atcMap.AirfieldBySpokenName(call.Addressee); addressed != nil {
+				target = addressed
+			}
+		}
+		if target == airfield {
+			for _, facility := range c.Facilities() {
+				if airfield.HandledBy(facility) == listening {
+					return cmd
+				}
+			}
+		}
+
+		facility := target.HandledBy(c.Facilities()[0])
+		contactOn, ok := target.ContactFrequency(facility, calledOn)
+		if !ok {
+			return cmd
+		}
+		log.Info().Msgf("%s %s can't handle %s, sending pilot to %s %s", airfield.Name, listening, cmd, target.Name, facility)
+		return &ContactFacility{Message: msg, Call: *call, Airfield: target, Facility: facility, Frequency: contactOn,
+			Request: intentOf(cmd)}
+	}
+	return cmd
+}
+
+// commands that know which request they answer, so we can ask the pilot which one they meant
+type intentCommand interface {
+	Intent() Intent
+}
+
+func intentOf(cmd PlayerCommand) Intent {
+	if c, ok := cmd.(intentCommand); ok {
+		return c.Intent()
+	}
+	return IntentUnknown
+}
+
+// scoreCall rates how sure we are that the call is for the intent. The request has to be in there somewhere,
+// and a callsign and calling the right facility, or one that stands in for it, make it more likely, calling the
+// wrong one less so. Pass no facilities if any will do.
+func scoreCall(call *RadioCall, intent Intent, facilities ...atcmodel.Facility) float64 {
+	if !call.HasIntent(intent) {
+		return 0
+	}
+
+	confidence := 0.6
+	if call.Intent != intent {
+		// something else in the call sounded more like the request
+		confidence -= 0.2
+	}
+	if call.HasCallsign {
+		confidence += 0.2
+	}
+	if call.Facility != "" {
+		called := atcmodel.ParseFacility(call.Facility)
+		rightFacility := len(facilities) == 0
+		for _, facility := range facilities {
+			rightFacility = rightFacility || facility == called || slices.Contains(facility.StandIns(), called)
+		}
+		if rightFacility {
+			confidence += 0.1
 		} else {
-			log.Info().Msgf("Could not match to command")
+			confidence -= 0.1
 		}
 	}
-	return PlayerCommandMessage{}, fmt.Errorf("no parser found for command: %s", message.Data)
+	return math.Round(confidence*100) / 100
 }
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessText_Confidence(t *testing.T) {
+	cp := NewCommandProcessor(&MockGenerator{})
+	cp.RegisterParser(&RadioCheckParser{})
+	cp.RegisterParser(&RequestTaxiParser{})
+	cp.RegisterParser(&RequestTakeoffParser{})
+	cp.RegisterParser(&StartUpEnginesParser{})
+	cp.RegisterParser(&InboundForLandingParser{})
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		reply    string
+	}{
+		{name: "clear request", input: "anapa ground, hawg 3-1, request taxi", expected: "RequestTaxiCommand"},
+		{name: "the right facility breaks the tie", input: "anapa tower hawg 3 1 holding short, taxi complete, ready for takeoff",
+			expected: "RequestTakeoffCommand"},
+		{name: "nothing recognisable", input: "anapa tower, blah blah", expected: "SayAgainCommand",
+			reply: "station calling anapa tower, say again"},
+		{name: "callsign but no request", input: "anapa tower, hawg 3-1, uh", expected: "SayAgainCommand",
+			reply: "hawg 3 1, say again"},
+		{name: "too close to call", input: "anapa ground hawg 3 1 request taxi, takeoff", expected: "SayAgainCommand",
+			reply: "hawg 3 1, confirm you are requesting taxi or takeoff"},
+		{name: "low confidence", input: "departure, request taxi then inbound", expected: "SayAgainCommand",
+			reply: "station calling departure, say again"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := message.Message[string]{Context: context.Background(), ClientName: "hawg31", Data: tt.input}
+			result, err := cp.ProcessText(context.Background(), &msg)
+			assert.Equal(t, tt.expected, result.ParsedCommand.(interface{ String() string }).String())
+			if tt.reply == "" {
+				assert.Nil(t, err)
+				return
+			}
+
+			assert.True(t, errors.Is(err, ErrNotUnderstood))
+			outChan := make(chan message.OutgoingMessage, 1)
+			assert.Nil(t, result.ParsedCommand.Execute(nil, outChan))
+			assert.Equal(t, tt.reply, (<-outChan).Message.Data)
+		})
+	}
+
+	// readbacks don't get an answer
+	msg := message.Message[string]{Context: context.Background(), ClientName: "hawg31", Data: "wilco, hawg 3 1"}
+	result, err := cp.ProcessText(context.Background(), &msg)
+	assert.NotNil(t, err)
+	assert.Nil(t, result.ParsedCommand)
+}
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/martinlindhe/unit"
+)
+
+// "Hawg 3-1, contact Anapa ground on 250.1"
+
+// ContactFacility sends a pilot who called the wrong controller to the one that handles their request
+type ContactFacility struct {
+	Message   *message.Message[string]
+	Call      RadioCall
+	Airfield  *atcmodel.Airfield
+	Facility  atcmodel.Facility
+	Frequency unit.Frequency
+	// what the pilot asked for
+	Request Intent
+}
+
+func (m *ContactFacility) String() string {
+	return "ContactFacilityCommand"
+}
+
+func (m *ContactFacility) Intent() Intent {
+	return m.Request
+}
+
+func (m *ContactFacility) Execute(atc *atcmodel.AtcModel, messageOut chan message.OutgoingMessage) error {
+	fmt.Printf("[execute] contact facility %s", m.Message.ClientName)
+
+	callsign, _, _ := resolveCaller(atc, m.Message)
+	messageText := fmt.Sprintf("%s, contact %s %s on %s", callsign, strings.ToLower(m.Airfield.Name), m.Facility,
+		atcmodel.FrequencyPhrase(m.Frequency))
+
+	messageOut <- message.OutgoingMessage{
+		Message: message.FromMessage(m.Message.Context, m.Message, messageText),
+	}
+	return nil
+}
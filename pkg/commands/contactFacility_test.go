@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/simpleradio/voice"
+	"github.com/martinlindhe/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFacilityRouting(t *testing.T) {
+	atcMap, err := atcmodel.LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	// as a mission would staff them, DCS only publishes tower frequencies
+	mhz := func(frequencies ...float64) []unit.Frequency {
+		result := []unit.Frequency{}
+		for _, f := range frequencies {
+			result = append(result, unit.Frequency(f)*unit.Megahertz)
+		}
+		return result
+	}
+	atcMap.AirfieldByName("Anapa").SetFacilityFrequencies(atcmodel.FacilityGround, mhz(250.1, 121.05))
+	atcMap.AirfieldByName("Kobuleti").SetFacilityFrequencies(atcmodel.FacilityGround, mhz(262.1))
+	atcMap.AirfieldByName("Batumi").SetFacilityFrequencies(atcmodel.FacilityGround, mhz(260.1))
+	atcMap.AirfieldByName("Batumi").SetFacilityFrequencies(atcmodel.FacilityApproach, mhz(260.3))
+
+	cp := NewCommandProcessor(&MockGenerator{})
+	cp.RegisterParser(&RadioCheckParser{})
+	cp.RegisterParser(&RequestTaxiParser{})
+	cp.RegisterParser(&StartUpEnginesParser{})
+	cp.RegisterParser(&RequestTakeoffParser{})
+	cp.RegisterParser(&InboundForLandingParser{})
+	cp.SetMap(&atcMap)
+
+	tests := []struct {
+		name      string
+		input     string
+		frequency unit.Frequency
+		expected  string
+		reply     string
+	}{
+		{name: "ground handles taxi", input: "anapa ground, hawg 3-1, request taxi", frequency: 250.1 * unit.Megahertz,
+			expected: "RequestTaxiCommand"},
+		{name: "taxi on tower", input: "anapa tower, hawg 3-1, request taxi", frequency: 250 * unit.Megahertz,
+			expected: "ContactFacilityCommand", reply: "hawg 3 1, contact anapa ground on 250.1"},
+		{name: "tower doubles as ground", input: "krymsk tower, hawg 3-1, request taxi", frequency: 124 * unit.Megahertz,
+			expected: "RequestTaxiCommand"},
+		{name: "takeoff on ground", input: "anapa ground hawg 3 1 ready for takeoff", frequency: 121.05 * unit.Megahertz,
+			expected: "ContactFacilityCommand", reply: "hawg 3 1, contact anapa tower on 121"},
+		{name: "another airfield", input: "kobuleti ground, enfield 1 1, request taxi", frequency: 250.1 * unit.Megahertz,
+			expected: "ContactFacilityCommand", reply: "enfield 1 1, contact kobuleti ground on 262.1"},
+		{name: "tower takes arrivals", input: "batumi tower, uzi 2 1, inbound full stop", frequency: 260 * unit.Megahertz,
+			expected: "InboundForLandingCommand"},
+		{name: "inbound on ground", input: "batumi ground, uzi 2 1, inbound full stop", frequency: 260.1 * unit.Megahertz,
+			expected: "ContactFacilityCommand", reply: "uzi 2 1, contact batumi approach on 260.3"},
+		{name: "anyone answers a radio check", input: "anapa ground, hawg 3-1, radio check", frequency: 250.1 * unit.Megahertz,
+			expected: "RadioCheckCommand"},
+		{name: "not an airfield frequency", input: "anapa tower, hawg 3-1, request taxi", frequency: 305 * unit.Megahertz,
+			expected: "RequestTaxiCommand"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := message.Message[string]{Context: context.Background(), ClientName: "player", Data: tt.input,
+				Frequencies: []voice.Frequency{{Frequency: tt.frequency.Hertz()}}}
+			result, err := cp.ProcessText(context.Background(), &msg)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, result.ParsedCommand.(interface{ String() string }).String())
+			if tt.reply == "" {
+				return
+			}
+
+			outChan := make(chan message.OutgoingMessage, 1)
+			assert.Nil(t, result.ParsedCommand.Execute(nil, outChan))
+			assert.Equal(t, tt.reply, (<-outChan).Message.Data)
+		})
+	}
+}
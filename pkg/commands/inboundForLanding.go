@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/martinlindhe/unit"
+)
+
+// "Anapa tower, Hawg 3-1, ten miles west, inbound for landing"
+
+// approach sequences arrivals, tower takes them once they're close
+var inboundFacilities = []atcmodel.Facility{atcmodel.FacilityApproach, atcmodel.FacilityTower}
+
+type InboundForLandingParser struct {
+}
+
+type InboundForLanding struct {
+	Message       *message.Message[string]
+	globalContext *GlobalCommandContext
+}
+
+func (m *InboundForLanding) String() string {
+	return "InboundForLandingCommand"
+}
+
+func (m *InboundForLanding) Intent() Intent {
+	return IntentInbound
+}
+
+func (m *InboundForLanding) Facilities() []atcmodel.Facility {
+	return inboundFacilities
+}
+
+func (m *InboundForLanding) reply(messageOut chan message.OutgoingMessage, text string) {
+	messageOut <- message.OutgoingMessage{
+		Message: message.FromMessage(m.Message.Context, m.Message, text),
+	}
+}
+
+// requestedEntry is the pattern entry the pilot asked for, if any
+func (m *InboundForLanding) requestedEntry() (atcmodel.PatternEntry, bool) {
+	switch {
+	case strings.Contains(m.Message.Data, "straight in"), strings.Contains(m.Message.Data, "straight-in"):
+		return atcmodel.EntryStraightIn, true
+	case strings.Contains(m.Message.Data, "overhead"), strings.Contains(m.Message.Data, "break"):
+		return atcmodel.EntryOverhead, true
+	case strings.Contains(m.Message.Data, "downwind"):
+		return atcmodel.EntryDownwind, true
+	}
+	return atcmodel.EntryOverhead, false
+}
+
+// answeredBy is the facility the pilot called, or whoever sequences arrivals if they didn't call on one of the
+// airfield's frequencies
+func (m *InboundForLanding) answeredBy(airfield *atcmodel.Airfield) atcmodel.Facility {
+	for _, frequency := range m.Message.Frequencies {
+		if facility, ok := airfield.FacilityOn(unit.Frequency(frequency.Frequency) * unit.Hertz); ok {
+			return facility
+		}
+	}
+	if facility := airfield.HandledBy(inboundFacilities[0]); facility != atcmodel.FacilityUnknown {
+		return facility
+	}
+	return atcmodel.FacilityTower
+}
+
+func (m *InboundForLanding) Execute(atc *atcmodel.AtcModel, messageOut chan message.OutgoingMessage) error {
+	fmt.Printf("[execute] inbound for landing %s", m.Message.ClientName)
+
+	callsign, planeId, found := resolveCaller(atc, m.Message)
+	if !found {
+		m.reply(messageOut, fmt.Sprintf("%s, unable to locate your aircraft, say again", callsign))
+		return fmt.Errorf("no plane found for %s", callsign)
+	}
+
+	plane := atc.AllPlaneData[planeId]
+	if !atc.PlaneState(planeId).State().IsAirborne() {
+		m.reply(messageOut, fmt.Sprintf("%s, you are on the ground", callsign))
+		return nil
+	}
+
+	// the pilot may be calling a field other than the closest one
+	airfield := atc.Map.AirfieldBySpokenName(m.Message.Data)
+	if airfield == nil {
+		airfield, _ = atc.Map.NearestAirfield(plane.Frame.Point)
+	}
+	if airfield == nil {
+		return fmt.Errorf("no airfields loaded")
+	}
+	runway := airfield.ActiveRunway()
+	if runway == nil {
+		return fmt.Errorf("no runways at %s", airfield.Name)
+	}
+
+	entry, requested := m.requestedEntry()
+	if !requested {
+		entry = atcmodel.ChoosePatternEntry(plane.Frame.Point, runway)
+	}
+
+	atc.AddArrival(&atcmodel.Arrival{
+		PlaneId:  planeId,
+		Airfield: airfield,
+		Runway:   runway,
+		Entry:    entry,
+		Request:  *m.Message,
+		Callsign: callsign,
+	})
+
+	instruction := ""
+	switch entry {
+	case atcmodel.EntryStraightIn:
+		instruction = fmt.Sprintf("straight-in runway %s approved, report final", runway.Designator)
+	case atcmodel.EntryDownwind:
+		instruction = fmt.Sprintf("enter downwind runway %s", runway.Designator)
+	default:
+		instruction = fmt.Sprintf("runway %s, overhead break approved", runway.Designator)
+	}
+
+	weather := atc.WeatherAt(airfield)
+	messageText := fmt.Sprintf("%s, %s %s, %s, %s, %s", callsign, strings.ToLower(airfield.Name), m.answeredBy(airfield),
+		instruction, weather.WindPhrase(atc.Declination(airfield)), weather.AltimeterPhrase())
+	m.reply(messageOut, messageText)
+
+	return nil
+}
+
+func (p *InboundForLandingParser) Parse(globalContext *GlobalCommandContext, message *message.Message[string], call *RadioCall) (PlayerCommand, float64) {
+	confidence := scoreCall(call, IntentInbound, inboundFacilities...)
+	if confidence > 0 {
+		return &InboundForLanding{globalContext: globalContext, Message: message}, confidence
+	}
+	return nil, 0
+}
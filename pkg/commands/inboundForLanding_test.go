@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/simpleradio/voice"
+	"github.com/dharmab/skyeye/pkg/trackfiles"
+	"github.com/martinlindhe/unit"
+)
+
+func TestInboundForLanding(t *testing.T) {
+	atcMap, err := atcmodel.LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	anapa := atcMap.AirfieldByName("Anapa")
+	runway04 := anapa.RunwayEndByDesignator("04")
+
+	west := trackfiles.Frame{Point: pointFrom(anapa.Position, 270*unit.Degree, 15*unit.NauticalMile), Heading: 90 * unit.Degree, AGL: ptrlength(5000 * unit.Foot)}
+	southwest := trackfiles.Frame{Point: pointFrom(runway04.Threshold, 222*unit.Degree, 10*unit.NauticalMile), Heading: 42 * unit.Degree, AGL: ptrlength(3000 * unit.Foot)}
+
+	tests := []struct {
+		name          string
+		input         string
+		frame         trackfiles.Frame
+		expectedText  string
+		expectedEntry atcmodel.PatternEntry
+	}{
+		{
+			name:          "overhead by default",
+			input:         "anapa tower hawg 3 1 15 miles west inbound for landing",
+			frame:         west,
+			expectedText:  "hawg 3 1, anapa tower, runway 04, overhead break approved, wind calm, altimeter 2992",
+			expectedEntry: atcmodel.EntryOverhead,
+		},
+		{
+			name:          "straight-in when lined up",
+			input:         "anapa tower hawg 3 1 inbound full stop",
+			frame:         southwest,
+			expectedText:  "hawg 3 1, anapa tower, straight-in runway 04 approved, report final, wind calm, altimeter 2992",
+			expectedEntry: atcmodel.EntryStraightIn,
+		},
+		{
+			name:          "requested downwind",
+			input:         "anapa tower hawg 3 1 inbound request downwind entry",
+			frame:         west,
+			expectedText:  "hawg 3 1, anapa tower, enter downwind runway 04, wind calm, altimeter 2992",
+			expectedEntry: atcmodel.EntryDownwind,
+		},
+	}
+
+	cp := NewCommandProcessor(&MockGenerator{})
+	cp.RegisterParser(&InboundForLandingParser{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := atcmodel.NewAtcModel(atcMap)
+			addPlane(model, 1, "hawg31", tt.frame)
+
+			msg := message.Message[string]{
+				Context:    context.Background(),
+				TraceId:    "trace1",
+				ClientName: "hawg31",
+				Data:       tt.input,
+			}
+			result, err := cp.ProcessText(context.Background(), &msg)
+			assert.Nil(t, err)
+
+			outChan := make(chan message.OutgoingMessage, 10)
+			assert.Nil(t, result.ParsedCommand.Execute(model, outChan))
+			response := <-outChan
+			assert.Equal(t, tt.expectedText, response.Message.Data)
+
+			arrival, ok := model.Arrivals[1]
+			assert.True(t, ok, "plane should be tracked as an arrival")
+			assert.Equal(t, tt.expectedEntry, arrival.Entry)
+			assert.Equal(t, runway04, arrival.Runway)
+		})
+	}
+}
+
+func TestInboundForLanding_AnsweredBy(t *testing.T) {
+	atcMap, err := atcmodel.LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	anapa := atcMap.AirfieldByName("Anapa")
+	anapa.SetFacilityFrequencies(atcmodel.FacilityApproach, []unit.Frequency{250.3 * unit.Megahertz})
+	west := trackfiles.Frame{Point: pointFrom(anapa.Position, 270*unit.Degree, 15*unit.NauticalMile), Heading: 90 * unit.Degree, AGL: ptrlength(5000 * unit.Foot)}
+
+	tests := []struct {
+		name         string
+		frequency    unit.Frequency
+		expectedText string
+	}{
+		{
+			name:         "on the approach frequency",
+			frequency:    250.3 * unit.Megahertz,
+			expectedText: "hawg 3 1, anapa approach, runway 04, overhead break approved, wind calm, altimeter 2992",
+		},
+		{
+			name:         "on the tower frequency",
+			frequency:    121 * unit.Megahertz,
+			expectedText: "hawg 3 1, anapa tower, runway 04, overhead break approved, wind calm, altimeter 2992",
+		},
+		{
+			name:         "approach sequences calls on other frequencies",
+			frequency:    305 * unit.Megahertz,
+			expectedText: "hawg 3 1, anapa approach, runway 04, overhead break approved, wind calm, altimeter 2992",
+		},
+	}
+
+	cp := NewCommandProcessor(&MockGenerator{})
+	cp.RegisterParser(&InboundForLandingParser{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := atcmodel.NewAtcModel(atcMap)
+			addPlane(model, 1, "hawg31", west)
+
+			msg := message.Message[string]{
+				Context:     context.Background(),
+				TraceId:     "trace1",
+				ClientName:  "hawg31",
+				Frequencies: []voice.Frequency{{Frequency: tt.frequency.Hertz()}},
+				Data:        "anapa hawg 3 1 15 miles west inbound for landing",
+			}
+			result, err := cp.ProcessText(context.Background(), &msg)
+			assert.Nil(t, err)
+
+			outChan := make(chan message.OutgoingMessage, 10)
+			assert.Nil(t, result.ParsedCommand.Execute(model, outChan))
+			response := <-outChan
+			assert.Equal(t, tt.expectedText, response.Message.Data)
+		})
+	}
+}
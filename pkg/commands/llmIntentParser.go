@@ -0,0 +1,230 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/rs/zerolog/log"
+)
+
+// Speech recognition mangles radio calls in ways phrase matching can't always follow, e.g. "anapa ground hog
+// three one ready to roll", so a language model can classify the call instead. Anything that speaks the OpenAI
+// chat completions API works, including a local llama.cpp server. The model only picks the intent, the
+// rule-based parsers still build the command, and if the server is down they carry on without it. When the
+// model says the call isn't a request at all, that outweighs any phrases the rules matched in it.
+
+const (
+	DEFAULT_LLM_TIMEOUT = 5 * time.Second
+	// after a failed request we leave the server alone for this long rather than slow down every call
+	LLM_RETRY_INTERVAL = 30 * time.Second
+)
+
+var llmSystemPrompt = `You classify radio calls from pilots to air traffic control in DCS World. The transcript
+comes from speech recognition, so expect misheard words, especially in callsigns. You are also given the
+aircraft and airfields the controller is tracking.
+
+Reply with a JSON object and nothing else: {"intent": "<intent>", "confidence": <0 to 1>}
+where intent is one of: ` + strings.Join(llmIntentNames(), ", ") + `.
+Use "unknown" if the pilot isn't asking for any of these, e.g. a readback or an acknowledgement.`
+
+var llmIntents = []Intent{IntentRadioCheck, IntentStartUp, IntentTaxi, IntentTakeoff, IntentInbound, IntentRepeat}
+
+func llmIntentNames() []string {
+	names := []string{}
+	for _, intent := range llmIntents {
+		names = append(names, fmt.Sprintf("%q", intent.String()))
+	}
+	return append(names, fmt.Sprintf("%q", IntentUnknown.String()))
+}
+
+// LLMIntentParser asks a language model what the pilot wants, then builds the command with the parser for
+// that intent
+type LLMIntentParser struct {
+	// base URL of the API, e.g. "https://api.openai.com/v1" or "http://localhost:8080/v1" for llama.cpp
+	Endpoint string
+	Model    string
+	// empty for local servers that don't need one
+	APIKey string
+	// builds the command once the model has picked an intent
+	Parsers []PlayerCommandParser
+	// the traffic picture to send with each call, may be nil
+	Snapshot func() *atcmodel.Snapshot
+
+	client *http.Client
+
+	mu               sync.Mutex
+	unavailableUntil time.Time
+}
+
+func NewLLMIntentParser(endpoint string, model string, apiKey string, timeout time.Duration,
+	snapshot func() *atcmodel.Snapshot, parsers ...PlayerCommandParser) *LLMIntentParser {
+	if timeout <= 0 {
+		timeout = DEFAULT_LLM_TIMEOUT
+	}
+	return &LLMIntentParser{
+		Endpoint: strings.TrimSuffix(endpoint, "/"),
+		Model:    model,
+		APIKey:   apiKey,
+		Parsers:  parsers,
+		Snapshot: snapshot,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+type llmChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type llmChatRequest struct {
+	Model          string            `json:"model"`
+	Messages       []llmChatMessage  `json:"messages"`
+	Temperature    float64           `json:"temperature"`
+	ResponseFormat map[string]string `json:"response_format"`
+}
+
+type llmChatResponse struct {
+	Choices []struct {
+		Message llmChatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// what we send as the user message
+type llmCallContext struct {
+	Transcript string             `json:"transcript"`
+	Speaker    string             `json:"speaker"`
+	Traffic    *atcmodel.Snapshot `json:"traffic,omitempty"`
+}
+
+// what the model sends back
+type llmIntent struct {
+	Intent     string  `json:"intent"`
+	Confidence float64 `json:"confidence"`
+}
+
+func (p *LLMIntentParser) Parse(globalContext *GlobalCommandContext, message *message.Message[string], call *RadioCall) (PlayerCommand, float64) {
+	if !p.available() {
+		return nil, 0
+	}
+
+	result, err := p.classify(message)
+	if err != nil {
+		log.Warn().Err(err).Msgf("LLM intent classification failed, using rule-based parsers for %s", LLM_RETRY_INTERVAL)
+		p.mu.Lock()
+		p.unavailableUntil = time.Now().Add(LLM_RETRY_INTERVAL)
+		p.mu.Unlock()
+		return nil, 0
+	}
+
+	intent := IntentUnknown
+	for _, candidate := range llmIntents {
+		if strings.EqualFold(strings.TrimSpace(result.Intent), candidate.String()) {
+			intent = candidate
+		}
+	}
+	if intent == IntentUnknown {
+		// a readback has the instruction in it, which the rule-based parsers would take for the request
+		if strings.EqualFold(strings.TrimSpace(result.Intent), IntentUnknown.String()) {
+			log.Info().Msgf("LLM classified call as no request with confidence %.2f", result.Confidence)
+			return &NoRequest{}, math.Max(0, math.Min(1, result.Confidence))
+		}
+		return nil, 0
+	}
+	log.Info().Msgf("LLM classified call as %s with confidence %.2f", intent, result.Confidence)
+
+	// the rule-based parsers only build a command for a call with their intent in it
+	classified := *call
+	classified.Intent = intent
+	classified.Intents = []Intent{intent}
+	for _, parser := range p.Parsers {
+		if cmd, _ := parser.Parse(globalContext, message, &classified); cmd != nil && intentOf(cmd) == intent {
+			return cmd, math.Max(0, math.Min(1, result.Confidence))
+		}
+	}
+	return nil, 0
+}
+
+func (p *LLMIntentParser) available() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return time.Now().After(p.unavailableUntil)
+}
+
+func (p *LLMIntentParser) classify(msg *message.Message[string]) (llmIntent, error) {
+	callContext := llmCallContext{Transcript: msg.Data, Speaker: msg.ClientName}
+	if p.Snapshot != nil {
+		callContext.Traffic = p.Snapshot()
+	}
+	userContent, err := json.Marshal(callContext)
+	if err != nil {
+		return llmIntent{}, err
+	}
+
+	body, err := json.Marshal(llmChatRequest{
+		Model: p.Model,
+		Messages: []llmChatMessage{
+			{Role: "system", Content: llmSystemPrompt},
+			{Role: "user", Content: string(userContent)},
+		},
+		ResponseFormat: map[string]string{"type": "json_object"},
+	})
+	if err != nil {
+		return llmIntent{}, err
+	}
+
+	ctx := msg.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Endpoint+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return llmIntent{}, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		request.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return llmIntent{}, fmt.Errorf("error calling %s: %w", p.Endpoint, err)
+	}
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return llmIntent{}, fmt.Errorf("error reading response from %s: %w", p.Endpoint, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return llmIntent{}, fmt.Errorf("%s returned %s: %s", p.Endpoint, response.Status, responseBody)
+	}
+
+	var chat llmChatResponse
+	if err := json.Unmarshal(responseBody, &chat); err != nil {
+		return llmIntent{}, fmt.Errorf("error parsing response from %s: %w", p.Endpoint, err)
+	}
+	if len(chat.Choices) == 0 {
+		return llmIntent{}, fmt.Errorf("no choices in response from %s", p.Endpoint)
+	}
+
+	// some models wrap the JSON in a code block or explain themselves despite being told not to
+	content := chat.Choices[0].Message.Content
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return llmIntent{}, fmt.Errorf("no JSON in reply: %s", content)
+	}
+	var result llmIntent
+	if err := json.Unmarshal([]byte(content[start:end+1]), &result); err != nil {
+		return llmIntent{}, fmt.Errorf("error parsing reply %s: %w", content, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubLLM stands in for an OpenAI-compatible server, replying with the given content
+type stubLLM struct {
+	reply    string
+	status   int
+	requests []llmChatRequest
+	auth     string
+}
+
+func (s *stubLLM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/chat/completions" {
+		http.NotFound(w, r)
+		return
+	}
+	var request llmChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.requests = append(s.requests, request)
+	s.auth = r.Header.Get("Authorization")
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": s.reply}}},
+	})
+}
+
+func newStubProcessor(t *testing.T, stub *stubLLM) (*CommandProcessor, *LLMIntentParser) {
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	snapshot := func() *atcmodel.Snapshot {
+		return &atcmodel.Snapshot{
+			Airfields: []atcmodel.AirfieldSnapshot{{Name: "Anapa-Vityazevo", ActiveRunway: "04", Wind: "wind calm"}},
+			Aircraft:  []atcmodel.AircraftSnapshot{{Name: "Hawg 3-1", Type: "A-10C_2", State: "parked"}},
+		}
+	}
+	llm := NewLLMIntentParser(server.URL+"/v1/", "local", "", time.Second, snapshot,
+		&RadioCheckParser{}, &RequestTaxiParser{}, &StartUpEnginesParser{}, &RequestTakeoffParser{},
+		&InboundForLandingParser{})
+
+	cp := NewCommandProcessor(&MockGenerator{})
+	cp.RegisterParser(&RadioCheckParser{})
+	cp.RegisterParser(&RequestTaxiParser{})
+	cp.RegisterParser(&StartUpEnginesParser{})
+	cp.RegisterParser(&RequestTakeoffParser{})
+	cp.RegisterParser(&InboundForLandingParser{})
+	cp.RegisterParser(llm)
+	return cp, llm
+}
+
+func processText(cp *CommandProcessor, text string) (PlayerCommandMessage, error) {
+	msg := message.Message[string]{Context: context.Background(), ClientName: "hawg31", Data: text}
+	return cp.ProcessText(context.Background(), &msg)
+}
+
+func TestLLMIntentParser_Classifies(t *testing.T) {
+	stub := &stubLLM{reply: "```json\n{\"intent\": \"takeoff\", \"confidence\": 0.95}\n```"}
+	cp, _ := newStubProcessor(t, stub)
+
+	// the rule-based parsers can't tell what "ready to roll" means
+	result, err := processText(cp, "anapa tower, hawg 3 1, ready to roll")
+	assert.Nil(t, err)
+	assert.Equal(t, "RequestTakeoffCommand", result.ParsedCommand.(interface{ String() string }).String())
+
+	assert.Len(t, stub.requests, 1)
+	request := stub.requests[0]
+	assert.Equal(t, "local", request.Model)
+	assert.Equal(t, "json_object", request.ResponseFormat["type"])
+	assert.Empty(t, stub.auth)
+	var sent llmCallContext
+	assert.Nil(t, json.Unmarshal([]byte(request.Messages[1].Content), &sent))
+	assert.Equal(t, "anapa tower, hawg 3 1, ready to roll", sent.Transcript)
+	assert.Equal(t, "hawg31", sent.Speaker)
+	assert.Equal(t, "Hawg 3-1", sent.Traffic.Aircraft[0].Name)
+	assert.Equal(t, "04", sent.Traffic.Airfields[0].ActiveRunway)
+}
+
+func TestLLMIntentParser_AgreesWithRules(t *testing.T) {
+	stub := &stubLLM{reply: `{"intent": "taxi", "confidence": 0.82}`}
+	cp, _ := newStubProcessor(t, stub)
+
+	// the rules say the same thing with about the same confidence, which isn't ambiguous
+	result, err := processText(cp, "anapa ground, hawg 3-1, request taxi")
+	assert.Nil(t, err)
+	assert.Equal(t, "RequestTaxiCommand", result.ParsedCommand.(interface{ String() string }).String())
+}
+
+func TestLLMIntentParser_Unknown(t *testing.T) {
+	stub := &stubLLM{reply: `{"intent": "unknown", "confidence": 0.9}`}
+	cp, _ := newStubProcessor(t, stub)
+
+	// the rules find a taxi request in it, but the model knows better
+	result, err := processText(cp, "anapa ground, hawg 3 1, taxi to runway 04 via alpha")
+	assert.ErrorContains(t, err, "not a request")
+	assert.Nil(t, result.ParsedCommand)
+
+	// unless it isn't sure
+	stub.reply = `{"intent": "unknown", "confidence": 0.3}`
+	result, err = processText(cp, "anapa ground, hawg 3 1, taxi to runway 04 via alpha")
+	assert.Nil(t, err)
+	assert.Equal(t, "RequestTaxiCommand", result.ParsedCommand.(interface{ String() string }).String())
+}
+
+func TestLLMIntentParser_Cancelled(t *testing.T) {
+	stub := &stubLLM{reply: `{"intent": "taxi", "confidence": 0.9}`}
+	cp, _ := newStubProcessor(t, stub)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	msg := message.Message[string]{Context: ctx, ClientName: "hawg31", Data: "anapa ground, hawg 3 1, ready to roll"}
+	result, _ := cp.ProcessText(context.Background(), &msg)
+	assert.Empty(t, stub.requests, "the request isn't made once the call is cancelled")
+	assert.Equal(t, "SayAgainCommand", result.ParsedCommand.(interface{ String() string }).String())
+}
+
+func TestLLMIntentParser_FallsBack(t *testing.T) {
+	stub := &stubLLM{status: http.StatusServiceUnavailable}
+	cp, llm := newStubProcessor(t, stub)
+	llm.APIKey = "secret"
+
+	result, err := processText(cp, "anapa ground, hawg 3-1, request taxi")
+	assert.Nil(t, err)
+	assert.Equal(t, "RequestTaxiCommand", result.ParsedCommand.(interface{ String() string }).String())
+	assert.Equal(t, "Bearer secret", stub.auth)
+
+	// we don't keep asking a server that's down
+	result, err = processText(cp, "anapa tower, hawg 3-1, request takeoff")
+	assert.Nil(t, err)
+	assert.Equal(t, "RequestTakeoffCommand", result.ParsedCommand.(interface{ String() string }).String())
+	assert.Len(t, stub.requests, 1)
+}
@@ -0,0 +1,380 @@
+package commands
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/dharmab/skyeye/pkg/bearings"
+	"github.com/martinlindhe/unit"
+)
+
+// ATC calls follow the pattern "<who you're calling>, <who you are>, <what you want>", e.g.
+// "Anapa tower, Hawg 3-1, flight of two at parking 86 and 87, request startup". ParseRadioCall breaks a
+// transcript into those parts so parsers match on what the pilot asked for rather than any word that
+// happens to be in the transcript.
+
+type Intent int
+
+const (
+	IntentUnknown Intent = iota
+	IntentRadioCheck
+	IntentStartUp
+	IntentTaxi
+	IntentTakeoff
+	IntentInbound
+	// the pilot asking the controller to say again
+	IntentRepeat
+)
+
+func (i Intent) String() string {
+	switch i {
+	case IntentRadioCheck:
+		return "radio check"
+	case IntentStartUp:
+		return "startup"
+	case IntentTaxi:
+		return "taxi"
+	case IntentTakeoff:
+		return "takeoff"
+	case IntentInbound:
+		return "inbound"
+	case IntentRepeat:
+		return "say again"
+	default:
+		return "unknown"
+	}
+}
+
+// what each intent sounds like, in the order they're tried. Words separated by "|" are alternatives and a
+// trailing "?" makes a word optional
+var intentPhrases = []struct {
+	intent  Intent
+	phrases []string
+}{
+	// speech recognition often hears "check" as "jack" or "czech"
+	{intent: IntentRadioCheck, phrases: []string{"radio|comms|comm check|jack|czech", "how do you read", "how copy"}},
+	{intent: IntentRepeat, phrases: []string{"say again", "repeat last|your|instructions|transmission"}},
+	{intent: IntentInbound, phrases: []string{"inbound", "for landing", "request|requesting landing", "full stop"}},
+	{intent: IntentTakeoff, phrases: []string{"takeoff", "take off", "ready for departure", "request|requesting departure"}},
+	{intent: IntentStartUp, phrases: []string{"startup", "start up", "engine|engines start|startup",
+		"request|requesting start", "ready to start", "ready for start"}},
+	{intent: IntentTaxi, phrases: []string{"taxi"}},
+}
+
+// readbacks and acknowledgements, which don't need an answer
+var acknowledgementPhrases = []string{"wilco", "roger", "copy", "copies", "cleared", "approved", "hold short",
+	"will report", "affirm", "affirmative", "thanks", "thank you"}
+
+// a call with one of these is asking for something, even if it also says "roger" or "thanks"
+var requestPhrases = []string{"request|requesting", "ready", "inbound"}
+
+// words that end the addressee, e.g. "anapa tower"
+var facilities = map[string]bool{
+	"tower": true, "ground": true, "approach": true, "departure": true, "center": true, "control": true,
+	"clearance": true, "radar": true, "atis": true,
+}
+
+var runwaySides = map[string]string{"left": "L", "right": "R", "center": "C"}
+
+var pluralNumber = regexp.MustCompile(`^(\d+)s$`)
+
+// RadioCall is a transcript broken down into its parts. Anything the pilot didn't say is left empty.
+type RadioCall struct {
+	// airfield name as spoken, e.g. "anapa", empty if the pilot just said "tower"
+	Addressee string
+	// e.g. "tower" or "ground"
+	Facility    string
+	Callsign    Callsign
+	HasCallsign bool
+	// the most likely request, IntentUnknown if nothing matched
+	Intent Intent
+	// every request the call could be, most likely first
+	Intents []Intent
+	// the pilot is reading back or acknowledging an instruction rather than asking for anything, e.g.
+	// "cleared for takeoff runway 04, hawg 3 1"
+	Acknowledgement bool
+
+	// including the leader, 0 if not given
+	FlightSize int
+	// ACMI names
+	PlaneTypes []string
+	Parking    []string
+	// e.g. "04" or "13L"
+	Runway   string
+	Altitude *unit.Length
+	// nil if not given
+	Heading bearings.Bearing
+}
+
+// ParseRadioCall breaks down a transcript such as "anapa ground, hawg 3-1, request taxi"
+func ParseRadioCall(text string) RadioCall {
+	tokens := tokenize(text)
+	call := RadioCall{PlaneTypes: []string{}, Parking: []string{}}
+
+	// the addressee comes first, if the pilot gave one
+	for i := 0; i < len(tokens) && i < 4; i++ {
+		// "ready for departure" is a request, not who they're calling
+		if facilities[tokens[i]] && !(i > 0 && slices.Contains([]string{"for", "request", "requesting"}, tokens[i-1])) {
+			// some airfields are named like a facility, e.g. "krasnodar center tower"
+			for i+1 < len(tokens) && facilities[tokens[i+1]] {
+				i++
+			}
+			call.Addressee = strings.Join(tokens[:i], " ")
+			call.Facility = tokens[i]
+			tokens = tokens[i+1:]
+			break
+		}
+	}
+
+	call.Callsign, call.HasCallsign = ParseCallsign(strings.Join(tokens, " "))
+
+	for _, candidate := range intentPhrases {
+		for _, phrase := range candidate.phrases {
+			if _, _, ok := findPhrase(tokens, phrase); ok {
+				call.Intents = append(call.Intents, candidate.intent)
+				break
+			}
+		}
+	}
+	// "request taxi for takeoff" is asking to taxi, the takeoff comes later. "taxi complete, ready for takeoff"
+	// isn't.
+	if taxi, _, ok := findPhrase(tokens, "request|requesting|ready to? taxi"); ok {
+		for _, phrase := range []string{"for takeoff|departure", "for take off"} {
+			if takeoff, _, ok := findPhrase(tokens, phrase); ok && taxi < takeoff {
+				call.Intents = slices.DeleteFunc(call.Intents, func(intent Intent) bool { return intent == IntentTakeoff })
+			}
+		}
+	}
+	if len(call.Intents) > 0 {
+		call.Intent = call.Intents[0]
+	}
+	// a radio check "how copy" or a "say again" is never a readback
+	call.Acknowledgement = hasAnyPhrase(tokens, acknowledgementPhrases) && !hasAnyPhrase(tokens, requestPhrases) &&
+		!call.HasIntent(IntentRadioCheck) && !call.HasIntent(IntentRepeat)
+
+	call.FlightSize = parseFlightSize(tokens)
+	call.PlaneTypes = parsePlaneTypes(tokens)
+	call.Parking = parseParking(tokens)
+	call.Runway = parseRunway(tokens)
+	call.Altitude = parseAltitude(tokens)
+	call.Heading = parseHeading(tokens)
+	return call
+}
+
+func (c *RadioCall) HasIntent(intent Intent) bool {
+	for _, i := range c.Intents {
+		if i == intent {
+			return true
+		}
+	}
+	return false
+}
+
+// Addressed is true if the call was to a controller, or at least by someone with a callsign, rather than
+// chatter within a flight like "two, lead, push button two"
+func (c *RadioCall) Addressed() bool {
+	return c.Facility != "" || (c.HasCallsign && c.Callsign.Known())
+}
+
+// Station is who the pilot was calling, e.g. "anapa tower", or "" if they didn't say
+func (c *RadioCall) Station() string {
+	return strings.TrimSpace(c.Addressee + " " + c.Facility)
+}
+
+func tokenize(text string) []string {
+	text = strings.ToLower(text)
+	text = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return ' '
+	}, text)
+	tokens := strings.Fields(text)
+	for i, token := range tokens {
+		// "a-10s" -> "a 10"
+		if match := pluralNumber.FindStringSubmatch(token); match != nil {
+			tokens[i] = match[1]
+		}
+	}
+	return tokens
+}
+
+func hasAnyPhrase(tokens []string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if _, _, ok := findPhrase(tokens, phrase); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// findPhrase returns where the phrase first appears in the tokens
+func findPhrase(tokens []string, phrase string) (int, int, bool) {
+	words := strings.Fields(phrase)
+	for start := range tokens {
+		if end, ok := matchPhrase(tokens, start, words); ok {
+			return start, end, true
+		}
+	}
+	return 0, 0, false
+}
+
+func matchPhrase(tokens []string, position int, words []string) (int, bool) {
+	if len(words) == 0 {
+		return position, true
+	}
+	word, optional := strings.CutSuffix(words[0], "?")
+	if position < len(tokens) && matchWord(tokens[position], word) {
+		if end, ok := matchPhrase(tokens, position+1, words[1:]); ok {
+			return end, true
+		}
+	}
+	if optional {
+		return matchPhrase(tokens, position, words[1:])
+	}
+	return 0, false
+}
+
+// matchWord checks a token against alternatives like "engine|engines", allowing plurals
+func matchWord(token string, alternatives string) bool {
+	for _, alternative := range strings.Split(alternatives, "|") {
+		if token == alternative || token == alternative+"s" {
+			return true
+		}
+	}
+	return false
+}
+
+// numberAt reads a number spoken as digits or words, e.g. "270", "two seven zero" or "two"
+func numberAt(tokens []string, position int, maxDigits int) (int, int, bool) {
+	digits := ""
+	for position < len(tokens) && len(digits) < maxDigits {
+		token := tokens[position]
+		if digit, ok := spokenDigits[token]; ok {
+			digits += strconv.Itoa(digit)
+		} else if _, err := strconv.Atoi(token); err == nil && len(digits)+len(token) <= maxDigits {
+			digits += token
+		} else {
+			break
+		}
+		position++
+	}
+	if digits == "" {
+		return 0, position, false
+	}
+	n, _ := strconv.Atoi(digits)
+	return n, position, true
+}
+
+func parseFlightSize(tokens []string) int {
+	if _, end, ok := findPhrase(tokens, "flight of"); ok && end < len(tokens) {
+		if size, ok := atcmodel.ParseSpokenNumber(tokens[end]); ok && size > 0 {
+			return size
+		}
+	}
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i+1] == "ship" {
+			if size, ok := atcmodel.ParseSpokenNumber(tokens[i]); ok && size > 0 {
+				return size
+			}
+		}
+	}
+	return 0
+}
+
+func parsePlaneTypes(tokens []string) []string {
+	planeTypes := []string{}
+	for _, planeType := range atcmodel.PLANE_TYPES {
+		for _, spoken := range planeType.Spoken {
+			if _, _, ok := findPhrase(tokens, strings.Join(tokenize(spoken), " ")); ok {
+				planeTypes = append(planeTypes, planeType.ACMINames...)
+				break
+			}
+		}
+	}
+	return planeTypes
+}
+
+func parseParking(tokens []string) []string {
+	_, end, ok := findPhrase(tokens, "parking|stand|ramp position|spot?")
+	if !ok {
+		return []string{}
+	}
+	parking := []string{}
+	for position := end; position < len(tokens); position++ {
+		if tokens[position] == "and" {
+			continue
+		}
+		if _, err := strconv.Atoi(tokens[position]); err != nil {
+			break
+		}
+		parking = append(parking, tokens[position])
+	}
+	return parking
+}
+
+func parseRunway(tokens []string) string {
+	_, end, ok := findPhrase(tokens, "runway")
+	if !ok {
+		return ""
+	}
+	number, end, ok := numberAt(tokens, end, 2)
+	if !ok || number < 1 || number > 36 {
+		return ""
+	}
+	designator := strconv.Itoa(number)
+	if number < 10 {
+		designator = "0" + designator
+	}
+	if end < len(tokens) {
+		designator += runwaySides[tokens[end]]
+	}
+	return designator
+}
+
+func parseAltitude(tokens []string) *unit.Length {
+	if _, end, ok := findPhrase(tokens, "angels"); ok {
+		if thousands, _, ok := numberAt(tokens, end, 2); ok {
+			altitude := unit.Length(thousands) * 1000 * unit.Foot
+			return &altitude
+		}
+	}
+
+	if start, end, ok := findPhrase(tokens, "thousand"); ok && start > 0 {
+		thousands, err := strconv.Atoi(tokens[start-1])
+		if err != nil {
+			thousands, ok = spokenDigits[tokens[start-1]]
+			if !ok {
+				return nil
+			}
+		}
+		altitude := unit.Length(thousands) * 1000 * unit.Foot
+		if hundreds, next, ok := numberAt(tokens, end, 1); ok && next < len(tokens) && tokens[next] == "hundred" {
+			altitude += unit.Length(hundreds) * 100 * unit.Foot
+		}
+		return &altitude
+	}
+
+	if start, _, ok := findPhrase(tokens, "feet"); ok && start > 0 {
+		if feet, err := strconv.Atoi(tokens[start-1]); err == nil {
+			altitude := unit.Length(feet) * unit.Foot
+			return &altitude
+		}
+	}
+	return nil
+}
+
+func parseHeading(tokens []string) bearings.Bearing {
+	_, end, ok := findPhrase(tokens, "heading")
+	if !ok {
+		return nil
+	}
+	degrees, _, ok := numberAt(tokens, end, 3)
+	if !ok || degrees > 360 {
+		return nil
+	}
+	return bearings.NewTrueBearing(unit.Angle(degrees) * unit.Degree)
+}
@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/martinlindhe/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRadioCall_Intent(t *testing.T) {
+	tests := []struct {
+		input     string
+		addressee string
+		facility  string
+		intent    Intent
+	}{
+		{input: "anapa tower, uzi 2-1, radio check", addressee: "anapa", facility: "tower", intent: IntentRadioCheck},
+		{input: "tower, how do you read", facility: "tower", intent: IntentRadioCheck},
+		{input: "anapa tower, hawg 3-1, flight of two a-10s at parking 86 and 87, requesting startup and weather information",
+			addressee: "anapa", facility: "tower", intent: IntentStartUp},
+		{input: "tower enfield 1 1 request engine start", facility: "tower", intent: IntentStartUp},
+		{input: "tower hawg 3 1 engine fire, engine fire", facility: "tower", intent: IntentUnknown},
+		{input: "kobuleti ground enfield one one ready to taxi", addressee: "kobuleti", facility: "ground", intent: IntentTaxi},
+		{input: "Anapa tower, Hawg 3-1, runway 04, ready for takeoff", addressee: "anapa", facility: "tower", intent: IntentTakeoff},
+		{input: "hawg 3 1 ready for departure", intent: IntentTakeoff},
+		{input: "hawg31 requesting departure", intent: IntentTakeoff},
+		{input: "anapa ground, hawg 3 1, request taxi for takeoff", addressee: "anapa", facility: "ground", intent: IntentTaxi},
+		{input: "ground, colt 1 1, request taxi to runway 22 for departure", facility: "ground", intent: IntentTaxi},
+		{input: "krasnodar center tower hawg 3 1 inbound full stop", addressee: "krasnodar center", facility: "tower", intent: IntentInbound},
+		{input: "hawg 3 1 wilco", intent: IntentUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			call := ParseRadioCall(tt.input)
+			assert.Equal(t, tt.addressee, call.Addressee)
+			assert.Equal(t, tt.facility, call.Facility)
+			assert.Equal(t, tt.intent, call.Intent)
+		})
+	}
+}
+
+func TestParseRadioCall_Slots(t *testing.T) {
+	call := ParseRadioCall("tower viper 1 1 4-ship f-16 parking spots 12, 13, 14 and 15 request startup")
+	assert.True(t, call.HasCallsign)
+	assert.Equal(t, Callsign{Name: "viper", Flight: 1, Element: 1}, call.Callsign)
+	assert.Equal(t, 4, call.FlightSize)
+	assert.Equal(t, []string{"F-16C_50"}, call.PlaneTypes)
+	assert.Equal(t, []string{"12", "13", "14", "15"}, call.Parking)
+
+	call = ParseRadioCall("hawg31 requesting departure")
+	assert.True(t, call.HasCallsign)
+	assert.Equal(t, Callsign{Name: "hawg", Flight: 3, Element: 1}, call.Callsign)
+	assert.Equal(t, []Intent{IntentTakeoff}, call.Intents)
+
+	call = ParseRadioCall("anapa ground, hawg 3 1, request taxi for takeoff")
+	assert.Equal(t, []Intent{IntentTaxi}, call.Intents, "not a takeoff request as well")
+
+	call = ParseRadioCall("anapa tower hawg 3 1 holding short runway zero four, ready for takeoff")
+	assert.Equal(t, "04", call.Runway)
+	assert.Equal(t, 0, call.FlightSize)
+	assert.Empty(t, call.Parking)
+	assert.Nil(t, call.Altitude)
+	assert.Nil(t, call.Heading)
+
+	call = ParseRadioCall("approach hawg 3 1 heading two seven zero angels 5 inbound runway 13 left")
+	assert.Equal(t, "13L", call.Runway)
+	assert.InDelta(t, 270, call.Heading.Degrees(), 0.01)
+	assert.InDelta(t, 5000, call.Altitude.Feet(), 0.01)
+
+	call = ParseRadioCall("approach hawg 3 1 heading 090 at 3 thousand 5 hundred inbound")
+	assert.InDelta(t, 90, call.Heading.Degrees(), 0.01)
+	assert.InDelta(t, 3500, call.Altitude.Feet(), 0.01)
+
+	call = ParseRadioCall("approach hawg 3 1 at 2500 feet inbound")
+	assert.InDelta(t, (2500 * unit.Foot).Feet(), call.Altitude.Feet(), 0.01)
+}
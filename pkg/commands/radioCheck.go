@@ -2,7 +2,6 @@ package commands
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
 	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
@@ -22,9 +21,19 @@ func (m *RadioCheck) String() string {
 	return "RadioCheckCommand"
 }
 
+func (m *RadioCheck) Intent() Intent {
+	return IntentRadioCheck
+}
+
+// any controller answers a radio check
+func (m *RadioCheck) Facilities() []atcmodel.Facility {
+	return nil
+}
+
 func (m *RadioCheck) Execute(atc *atcmodel.AtcModel, messageOut chan message.OutgoingMessage) error {
 	fmt.Printf("[execute] radio check %s", m.Message.ClientName)
 
+	callsign, _, _ := resolveCaller(atc, m.Message)
 	intro := ""
 	if m.globalContext.rand.IntN(3) == 0 {
 		if m.Message.GameTimeHour <= 10 {
@@ -34,9 +43,9 @@ func (m *RadioCheck) Execute(atc *atcmodel.AtcModel, messageOut chan message.Out
 		} else {
 			intro = "evening"
 		}
-		intro = fmt.Sprintf("good %s, %s.", intro, m.Message.ClientName)
+		intro = fmt.Sprintf("good %s, %s.", intro, callsign)
 	} else {
-		intro = fmt.Sprintf("%s,", m.Message.ClientName)
+		intro = fmt.Sprintf("%s,", callsign)
 	}
 
 	bodyText := ""
@@ -56,15 +65,15 @@ func (m *RadioCheck) Execute(atc *atcmodel.AtcModel, messageOut chan message.Out
 
 	messageOut <- message.OutgoingMessage{
 		Message: message.FromMessage(m.Message.Context, m.Message, messageText),
-		Model:   "aura-asteria-en",
 	}
 
 	return nil
 }
 
-func (p *RadioCheckParser) Parse(globalContext *GlobalCommandContext, message *message.Message[string]) PlayerCommand {
-	if strings.Contains(message.Data, "radio check") {
-		return &RadioCheck{globalContext: globalContext, Message: message}
+func (p *RadioCheckParser) Parse(globalContext *GlobalCommandContext, message *message.Message[string], call *RadioCall) (PlayerCommand, float64) {
+	confidence := scoreCall(call, IntentRadioCheck)
+	if confidence > 0 {
+		return &RadioCheck{globalContext: globalContext, Message: message}, confidence
 	}
-	return nil
+	return nil, 0
 }
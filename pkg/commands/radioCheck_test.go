@@ -3,6 +3,7 @@ package commands
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -20,6 +21,7 @@ func TestRadioCheck(t *testing.T) {
 		shouldMatch  bool
 		expectedText string
 		randValues   []int
+		gameTime     time.Time
 	}{
 		{
 			name:         "basic radio check",
@@ -37,6 +39,15 @@ func TestRadioCheck(t *testing.T) {
 			shouldMatch:  true,
 			randValues:   []int{0, 2},
 		},
+		{
+			name:         "radio check in the evening",
+			input:        "radio check",
+			clientName:   "test4",
+			expectedText: "good evening, test4. lima charlie",
+			shouldMatch:  true,
+			randValues:   []int{0, 3},
+			gameTime:     time.Date(2024, 6, 1, 19, 30, 0, 0, time.UTC),
+		},
 		{
 			name:        "unrelated message",
 			input:       "request taxi",
@@ -67,6 +78,7 @@ func TestRadioCheck(t *testing.T) {
 				},
 				Data: tt.input,
 			}
+			msg.SetGameTime(tt.gameTime)
 			result, err := cp.ProcessText(context.Background(), &msg)
 
 			outChan := make(chan message.OutgoingMessage, 10)
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+)
+
+// "Anapa ground, Hawg 3-1, say again"
+
+type RepeatLastCallParser struct {
+}
+
+// RepeatLastCall is the pilot asking the controller to repeat whatever they last said to them
+type RepeatLastCall struct {
+	Message       *message.Message[string]
+	globalContext *GlobalCommandContext
+}
+
+func (m *RepeatLastCall) String() string {
+	return "RepeatLastCallCommand"
+}
+
+func (m *RepeatLastCall) Intent() Intent {
+	return IntentRepeat
+}
+
+// whoever made the call repeats it, which is whoever's on the frequency
+func (m *RepeatLastCall) Facilities() []atcmodel.Facility {
+	return nil
+}
+
+func (m *RepeatLastCall) Execute(atc *atcmodel.AtcModel, messageOut chan message.OutgoingMessage) error {
+	fmt.Printf("[execute] repeat last call %s", m.Message.ClientName)
+
+	callsign, _, _ := resolveCaller(atc, m.Message)
+	messageText := fmt.Sprintf("%s, nothing further", callsign)
+	if atc != nil {
+		if controller := atc.ControllerOn(m.Message.Frequencies); controller != nil {
+			if last, ok := controller.LastSaidTo(m.Message.ClientName); ok {
+				messageText = last.Message.Data
+			}
+		}
+	}
+
+	messageOut <- message.OutgoingMessage{
+		Message: message.FromMessage(m.Message.Context, m.Message, messageText),
+	}
+	return nil
+}
+
+func (p *RepeatLastCallParser) Parse(globalContext *GlobalCommandContext, message *message.Message[string], call *RadioCall) (PlayerCommand, float64) {
+	confidence := scoreCall(call, IntentRepeat)
+	if confidence > 0 {
+		return &RepeatLastCall{globalContext: globalContext, Message: message}, confidence
+	}
+	return nil, 0
+}
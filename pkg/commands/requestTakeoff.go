@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/martinlindhe/unit"
+)
+
+// "Anapa tower, Hawg 3-1, holding short runway 04, ready for takeoff"
+
+// initial climb above the field, rounded to the nearest 500 feet
+const DEPARTURE_ALTITUDE = 3000 * unit.Foot
+
+// only tower clears aircraft onto the runway
+var takeoffFacilities = []atcmodel.Facility{atcmodel.FacilityTower}
+
+type RequestTakeoffParser struct {
+}
+
+type RequestTakeoff struct {
+	Message       *message.Message[string]
+	globalContext *GlobalCommandContext
+}
+
+func (m *RequestTakeoff) String() string {
+	return "RequestTakeoffCommand"
+}
+
+func (m *RequestTakeoff) Intent() Intent {
+	return IntentTakeoff
+}
+
+func (m *RequestTakeoff) Facilities() []atcmodel.Facility {
+	return takeoffFacilities
+}
+
+func (m *RequestTakeoff) reply(messageOut chan message.OutgoingMessage, text string) {
+	messageOut <- message.OutgoingMessage{
+		Message: message.FromMessage(m.Message.Context, m.Message, text),
+	}
+}
+
+func (m *RequestTakeoff) Execute(atc *atcmodel.AtcModel, messageOut chan message.OutgoingMessage) error {
+	fmt.Printf("[execute] request takeoff %s", m.Message.ClientName)
+
+	callsign, planeId, found := resolveCaller(atc, m.Message)
+	if !found {
+		m.reply(messageOut, fmt.Sprintf("%s, unable to locate your aircraft, say again", callsign))
+		return fmt.Errorf("no plane found for %s", callsign)
+	}
+
+	plane := atc.AllPlaneData[planeId]
+	state := atc.PlaneState(planeId)
+	if state.State().IsAirborne() {
+		m.reply(messageOut, fmt.Sprintf("%s, you are airborne", callsign))
+		return nil
+	}
+
+	airfield := state.Airfield()
+	if airfield == nil {
+		airfield, _ = atc.Map.NearestAirfield(plane.Frame.Point)
+	}
+	if airfield == nil {
+		return fmt.Errorf("no airfields loaded")
+	}
+	active := airfield.ActiveRunway()
+	if active == nil {
+		return fmt.Errorf("no runways at %s", airfield.Name)
+	}
+
+	if state.State() != atcmodel.StateHoldingShort && state.State() != atcmodel.StateLinedUp {
+		m.reply(messageOut, fmt.Sprintf("%s, unable, taxi to runway %s and hold short", callsign, active.Designator))
+		return nil
+	}
+	if state.Runway() != active {
+		m.reply(messageOut, fmt.Sprintf("%s, unable, runway %s in use, taxi to runway %s and hold short",
+			callsign, active.Designator, active.Designator))
+		return nil
+	}
+
+	traffic, _ := atc.RunwayTrafficAt(airfield, active, m.flight(atc, planeId))
+	switch traffic {
+	case atcmodel.TrafficLanding:
+		m.reply(messageOut, fmt.Sprintf("%s, hold position, traffic landing", callsign))
+		return nil
+	case atcmodel.TrafficOnRunway:
+		m.reply(messageOut, fmt.Sprintf("%s, hold position, traffic on the runway", callsign))
+		return nil
+	}
+
+	weather := atc.WeatherAt(airfield)
+	declination := atc.Declination(airfield)
+	altitude := airfield.Elevation + DEPARTURE_ALTITUDE
+	altitudeFeet := int(math.Round(altitude.Feet()/500) * 500)
+
+	messageText := fmt.Sprintf("%s, %s, runway %s, cleared for takeoff, fly heading %s, climb and maintain %d",
+		callsign, weather.WindPhrase(declination), active.Designator, active.Heading.Magnetic(declination).String(),
+		altitudeFeet)
+	m.reply(messageOut, messageText)
+
+	return nil
+}
+
+// flight returns the caller and their wingmen, who can be on the runway with them
+func (m *RequestTakeoff) flight(atc *atcmodel.AtcModel, planeId uint64) map[uint64]bool {
+	flight := map[uint64]bool{planeId: true}
+	if squad, ok := atc.PlaneToSquad[planeId]; ok {
+		for wingmanId := range squad.PlaneStates {
+			flight[wingmanId] = true
+		}
+	}
+	return flight
+}
+
+func (p *RequestTakeoffParser) Parse(globalContext *GlobalCommandContext, message *message.Message[string], call *RadioCall) (PlayerCommand, float64) {
+	confidence := scoreCall(call, IntentTakeoff, takeoffFacilities...)
+	if confidence > 0 {
+		return &RequestTakeoff{globalContext: globalContext, Message: message}, confidence
+	}
+	return nil, 0
+}
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/simpleradio/types"
+	"github.com/dharmab/skyeye/pkg/trackfiles"
+	"github.com/martinlindhe/unit"
+)
+
+func TestRequestTakeoff(t *testing.T) {
+	atcMap, err := atcmodel.LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	anapa := atcMap.AirfieldByName("Anapa")
+	runway04 := anapa.RunwayEndByDesignator("04")
+	runway22 := anapa.RunwayEndByDesignator("22")
+
+	linedUp04 := trackfiles.Frame{Point: runway04.Threshold, Heading: 42 * unit.Degree, AGL: ptrlength(0)}
+
+	// runway 04 is 042 true, the heading is given magnetic
+	tests := []struct {
+		name         string
+		setup        func(model *atcmodel.AtcModel)
+		expectedText string
+	}{
+		{
+			name: "runway clear",
+			setup: func(model *atcmodel.AtcModel) {
+				addPlane(model, 1, "hawg31", linedUp04)
+			},
+			expectedText: "hawg 3 1, wind calm, runway 04, cleared for takeoff, fly heading 034, climb and maintain 3000",
+		},
+		{
+			name: "wingman lined up alongside",
+			setup: func(model *atcmodel.AtcModel) {
+				addPlane(model, 1, "hawg31", linedUp04)
+				addPlane(model, 2, "hawg32", trackfiles.Frame{Point: pointFrom(runway04.Threshold, 132*unit.Degree, 10*unit.Meter), Heading: 42 * unit.Degree, AGL: ptrlength(0)})
+				model.CreateSquad(1, []uint64{2}, types.Radio{})
+			},
+			expectedText: "hawg 3 1, wind calm, runway 04, cleared for takeoff, fly heading 034, climb and maintain 3000",
+		},
+		{
+			name: "traffic on final",
+			setup: func(model *atcmodel.AtcModel) {
+				addPlane(model, 1, "hawg31", linedUp04)
+				addPlane(model, 2, "viper11", trackfiles.Frame{
+					Point:   pointFrom(runway04.Threshold, 222*unit.Degree, 3*unit.NauticalMile),
+					Heading: 42 * unit.Degree,
+					AGL:     ptrlength(900 * unit.Foot),
+				})
+			},
+			expectedText: "hawg 3 1, hold position, traffic landing",
+		},
+		{
+			name: "traffic on the runway",
+			setup: func(model *atcmodel.AtcModel) {
+				addPlane(model, 1, "hawg31", linedUp04)
+				addPlane(model, 2, "viper11", trackfiles.Frame{Point: pointFrom(runway22.Threshold, 222*unit.Degree, 500*unit.Meter), Heading: 222 * unit.Degree, AGL: ptrlength(0)})
+			},
+			expectedText: "hawg 3 1, hold position, traffic on the runway",
+		},
+		{
+			name: "lined up on the wrong runway",
+			setup: func(model *atcmodel.AtcModel) {
+				addPlane(model, 1, "hawg31", trackfiles.Frame{Point: runway22.Threshold, Heading: 222 * unit.Degree, AGL: ptrlength(0)})
+			},
+			expectedText: "hawg 3 1, unable, runway 04 in use, taxi to runway 04 and hold short",
+		},
+		{
+			name: "still parked",
+			setup: func(model *atcmodel.AtcModel) {
+				addPlane(model, 1, "hawg31", trackfiles.Frame{Point: pointFrom(anapa.Position, 132*unit.Degree, 400*unit.Meter), AGL: ptrlength(0)})
+			},
+			expectedText: "hawg 3 1, unable, taxi to runway 04 and hold short",
+		},
+	}
+
+	cp := NewCommandProcessor(&MockGenerator{})
+	cp.RegisterParser(&RequestTakeoffParser{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := atcmodel.NewAtcModel(atcMap)
+			tt.setup(model)
+
+			msg := message.Message[string]{
+				Context:    context.Background(),
+				TraceId:    "trace1",
+				ClientName: "hawg31",
+				Data:       "Anapa tower, Hawg 3-1, runway 04, ready for takeoff",
+			}
+			result, err := cp.ProcessText(context.Background(), &msg)
+			assert.Nil(t, err)
+
+			outChan := make(chan message.OutgoingMessage, 10)
+			assert.Nil(t, result.ParsedCommand.Execute(model, outChan))
+
+			select {
+			case response := <-outChan:
+				assert.Equal(t, tt.expectedText, response.Message.Data)
+			case <-time.After(time.Second):
+				assert.Fail(t, "no response generated")
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/rs/zerolog/log"
+)
+
+// "Anapa ground, Hawg 3-1, request taxi"
+// the route is only given at airfields with taxiway data, which none of the bundled terrains have yet
+
+// ground gives taxi clearance
+var taxiFacilities = []atcmodel.Facility{atcmodel.FacilityGround}
+
+type RequestTaxiParser struct {
+}
+
+type RequestTaxi struct {
+	Message       *message.Message[string]
+	globalContext *GlobalCommandContext
+}
+
+func (m *RequestTaxi) String() string {
+	return "RequestTaxiCommand"
+}
+
+func (m *RequestTaxi) Intent() Intent {
+	return IntentTaxi
+}
+
+func (m *RequestTaxi) Facilities() []atcmodel.Facility {
+	return taxiFacilities
+}
+
+func (m *RequestTaxi) reply(messageOut chan message.OutgoingMessage, text string) {
+	messageOut <- message.OutgoingMessage{
+		Message: message.FromMessage(m.Message.Context, m.Message, text),
+	}
+}
+
+func (m *RequestTaxi) Execute(atc *atcmodel.AtcModel, messageOut chan message.OutgoingMessage) error {
+	fmt.Printf("[execute] request taxi %s", m.Message.ClientName)
+
+	callsign, planeId, found := resolveCaller(atc, m.Message)
+	if !found {
+		m.reply(messageOut, fmt.Sprintf("%s, unable to locate your aircraft, say again", callsign))
+		return fmt.Errorf("no plane found for %s", callsign)
+	}
+
+	plane := atc.AllPlaneData[planeId]
+	if atc.PlaneState(planeId).State().IsAirborne() {
+		m.reply(messageOut, fmt.Sprintf("%s, you are airborne, unable taxi", callsign))
+		return nil
+	}
+
+	airfield, _ := atc.Map.NearestAirfield(plane.Frame.Point)
+	if airfield == nil {
+		return fmt.Errorf("no airfields loaded")
+	}
+	runway := airfield.ActiveRunway()
+	if runway == nil {
+		return fmt.Errorf("no runways at %s", airfield.Name)
+	}
+
+	messageText := fmt.Sprintf("%s, taxi to runway %s", callsign, runway.Designator)
+	if airfield.Taxiways != nil {
+		route, err := airfield.Taxiways.RouteToRunway(plane.Frame.Point, runway)
+		if err != nil {
+			log.Warn().Err(err).Msgf("no taxi route at %s", airfield.Name)
+		} else if len(route.Taxiways) > 0 {
+			messageText = fmt.Sprintf("%s via %s", messageText, strings.Join(route.Taxiways, ", "))
+		}
+	}
+	messageText = fmt.Sprintf("%s, hold short", messageText)
+
+	m.reply(messageOut, messageText)
+
+	return nil
+}
+
+func (p *RequestTaxiParser) Parse(globalContext *GlobalCommandContext, message *message.Message[string], call *RadioCall) (PlayerCommand, float64) {
+	confidence := scoreCall(call, IntentTaxi, taxiFacilities...)
+	if confidence > 0 {
+		return &RequestTaxi{globalContext: globalContext, Message: message}, confidence
+	}
+	return nil, 0
+}
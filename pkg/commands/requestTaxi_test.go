@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/bearings"
+	"github.com/dharmab/skyeye/pkg/sim"
+	"github.com/dharmab/skyeye/pkg/spatial"
+	"github.com/dharmab/skyeye/pkg/trackfiles"
+	"github.com/martinlindhe/unit"
+	"github.com/paulmach/orb"
+)
+
+func addPlane(model *atcmodel.AtcModel, id uint64, name string, frame trackfiles.Frame) {
+	if frame.Time.IsZero() {
+		frame.Time = time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	}
+	model.AllPlaneData[id] = &sim.Updated{
+		Labels: trackfiles.Labels{ID: id, Name: name, ACMIName: "A-10C_2"},
+		Frame:  frame,
+	}
+	model.CallsignToId[name] = &model.AllPlaneData[id].Labels.ID
+	model.PlaneState(id).UpdateFromTrack(frame)
+}
+
+func pointFrom(origin orb.Point, heading unit.Angle, distance unit.Length) orb.Point {
+	return spatial.PointAtBearingAndDistance(origin, bearings.NewTrueBearing(heading), distance)
+}
+
+func ptrlength(l unit.Length) *unit.Length {
+	return &l
+}
+
+func TestRequestTaxi(t *testing.T) {
+	atcMap, err := atcmodel.LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+
+	anapa := atcMap.AirfieldByName("Anapa")
+	kobuleti := atcMap.AirfieldByName("Kobuleti")
+	batumi := atcMap.AirfieldByName("Batumi")
+
+	tests := []struct {
+		name         string
+		input        string
+		clientName   string
+		shouldMatch  bool
+		expectedText string
+	}{
+		{
+			name:         "parked at anapa apron",
+			input:        "anapa ground hawg three one request taxi",
+			clientName:   "hawg31",
+			shouldMatch:  true,
+			expectedText: "hawg 3 1, taxi to runway 04, hold short",
+		},
+		{
+			name:         "parked at kobuleti",
+			input:        "kobuleti ground enfield one one ready to taxi",
+			clientName:   "enfield11",
+			shouldMatch:  true,
+			expectedText: "enfield 1 1, taxi to runway 07, hold short",
+		},
+		{
+			name:         "parked at batumi",
+			input:        "batumi ground colt one one request taxi",
+			clientName:   "colt11",
+			shouldMatch:  true,
+			expectedText: "colt 1 1, taxi to runway 13, hold short",
+		},
+		{
+			name:         "airborne",
+			input:        "anapa tower dodge one one request taxi",
+			clientName:   "dodge11",
+			shouldMatch:  true,
+			expectedText: "dodge 1 1, you are airborne, unable taxi",
+		},
+		{
+			name:         "unknown plane",
+			input:        "anapa ground request taxi",
+			clientName:   "spectator",
+			shouldMatch:  true,
+			expectedText: "spectator, unable to locate your aircraft, say again",
+		},
+		{
+			name:        "unrelated message",
+			input:       "radio check",
+			clientName:  "hawg31",
+			shouldMatch: false,
+		},
+	}
+
+	model := atcmodel.NewAtcModel(atcMap)
+	addPlane(model, 1, "hawg31", trackfiles.Frame{Point: pointFrom(anapa.Position, 132*unit.Degree, 520*unit.Meter), AGL: ptrlength(0)})
+	addPlane(model, 2, "enfield11", trackfiles.Frame{Point: pointFrom(kobuleti.Position, 340*unit.Degree, 520*unit.Meter), AGL: ptrlength(0)})
+	addPlane(model, 3, "colt11", trackfiles.Frame{Point: pointFrom(batumi.Position, 36*unit.Degree, 400*unit.Meter), AGL: ptrlength(0)})
+	addPlane(model, 4, "dodge11", trackfiles.Frame{Point: pointFrom(anapa.Position, 0, 3*unit.NauticalMile), AGL: ptrlength(2000 * unit.Foot)})
+
+	cp := NewCommandProcessor(&MockGenerator{})
+	cp.RegisterParser(&RequestTaxiParser{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := message.Message[string]{
+				Context:    context.Background(),
+				TraceId:    "trace1",
+				ClientName: tt.clientName,
+				Data:       tt.input,
+			}
+			result, err := cp.ProcessText(context.Background(), &msg)
+
+			if !tt.shouldMatch {
+				assert.NotNil(t, err, "Expected error for non-matching input")
+				return
+			}
+			assert.Nil(t, err, "Expected match but got error: %v", err)
+
+			outChan := make(chan message.OutgoingMessage, 10)
+			result.ParsedCommand.Execute(model, outChan)
+
+			select {
+			case response := <-outChan:
+				assert.Equal(t, tt.expectedText, response.Message.Data, "Expected reply text to match")
+			default:
+				assert.Fail(t, "no response generated")
+			}
+		})
+	}
+}
+
+func TestRequestTaxi_Route(t *testing.T) {
+	// a made-up layout around Anapa's runway, the bundled terrains have no taxiway data
+	atcMap, err := atcmodel.LoadAtcMap(filepath.Join("..", "atcmodel", "testdata", "taxiways.json"))
+	assert.Nil(t, err)
+	airfield := atcMap.AirfieldByName("Test Field")
+
+	model := atcmodel.NewAtcModel(atcMap)
+	addPlane(model, 1, "hawg31", trackfiles.Frame{Point: pointFrom(airfield.Position, 132*unit.Degree, 520*unit.Meter), AGL: ptrlength(0)})
+
+	cp := NewCommandProcessor(&MockGenerator{})
+	cp.RegisterParser(&RequestTaxiParser{})
+	msg := message.Message[string]{Context: context.Background(), ClientName: "hawg31", Data: "test field ground hawg three one request taxi"}
+	result, err := cp.ProcessText(context.Background(), &msg)
+	assert.Nil(t, err)
+
+	outChan := make(chan message.OutgoingMessage, 1)
+	assert.Nil(t, result.ParsedCommand.Execute(model, outChan))
+	assert.Equal(t, "hawg 3 1, taxi to runway 04 via delta, charlie, alpha, hold short", (<-outChan).Message.Data)
+}
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+)
+
+// "Station calling Anapa tower, say again"
+
+// SayAgain is what the controller does with a call it didn't understand, rather than leave the pilot
+// listening to silence
+type SayAgain struct {
+	Message *message.Message[string]
+	Call    RadioCall
+	// the requests we couldn't choose between, if that was the problem
+	Choices []Intent
+}
+
+func (m *SayAgain) String() string {
+	return "SayAgainCommand"
+}
+
+func (m *SayAgain) Intent() Intent {
+	return IntentUnknown
+}
+
+func (m *SayAgain) Execute(atc *atcmodel.AtcModel, messageOut chan message.OutgoingMessage) error {
+	fmt.Printf("[execute] say again %s", m.Message.ClientName)
+
+	// without a callsign we can't be sure who it was
+	caller := "station calling"
+	if station := m.Call.Station(); station != "" {
+		caller = fmt.Sprintf("station calling %s", station)
+	}
+	if m.Call.HasCallsign {
+		caller = m.Call.Callsign.String()
+	}
+
+	messageText := fmt.Sprintf("%s, say again", caller)
+	if len(m.Choices) > 1 && m.Call.HasCallsign {
+		choices := make([]string, 0, len(m.Choices))
+		for _, choice := range m.Choices {
+			choices = append(choices, choice.String())
+		}
+		messageText = fmt.Sprintf("%s, confirm you are requesting %s", caller, strings.Join(choices, " or "))
+	}
+
+	messageOut <- message.OutgoingMessage{
+		Message: message.FromMessage(m.Message.Context, m.Message, messageText),
+	}
+	return nil
+}
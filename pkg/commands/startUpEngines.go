@@ -4,19 +4,36 @@ package commands
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
 	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/simpleradio/types"
+	"github.com/martinlindhe/unit"
+	"github.com/rs/zerolog/log"
 )
 
-// "Anapa tower, Uzi 2-1, radio check"
+// how far from the leader to look for the rest of the flight
+const SQUAD_SEARCH_DISTANCE = 500 * unit.Meter
+
+// ground, or clearance delivery which we treat as ground, approves startup
+var startUpFacilities = []atcmodel.Facility{atcmodel.FacilityGround}
 
 type StartUpEnginesParser struct {
 }
 
+// SquadronInfo is what the pilot told us about their flight
+type SquadronInfo struct {
+	// including the leader
+	FlightSize int
+	// empty if the pilot didn't say, ACMI names otherwise
+	PlaneTypes []string
+	// one spot for each plane in the flight
+	Parking []string
+}
+
 type StartUpEngines struct {
 	Message       *message.Message[string]
+	Squadron      SquadronInfo
 	globalContext *GlobalCommandContext
 }
 
@@ -24,58 +41,136 @@ func (m *StartUpEngines) String() string {
 	return "StartUpEnginesCommand"
 }
 
-func (m *StartUpEngines) Execute(atc *atcmodel.AtcModel, messageOut chan message.OutgoingMessage) error {
-	fmt.Printf("[execute] radio check %s", m.Message.ClientName)
-
-	intro := ""
-	if m.globalContext.rand.IntN(3) == 0 {
-		if m.Message.GameTimeHour <= 10 {
-			intro = "morning"
-		} else if m.Message.GameTimeHour <= 16 {
-			intro = "afternoon"
-		} else {
-			intro = "evening"
-		}
-		intro = fmt.Sprintf("good %s, %s.", intro, m.Message.ClientName)
-	} else {
-		intro = fmt.Sprintf("%s,", m.Message.ClientName)
-	}
-
-	bodyText := ""
-	variation := m.globalContext.rand.IntN(5)
-	if variation == 0 {
-		bodyText = "got you loud and clear"
-	} else if variation == 1 {
-		bodyText = "loud and clear"
-	} else if variation == 2 {
-		bodyText = "read you five by five"
-	} else if variation == 3 {
-		bodyText = "lima charlie"
-	} else if variation == 4 {
-		bodyText = "reading you loud and clear"
-	}
-	messageText := fmt.Sprintf("%s %s", intro, bodyText)
+func (m *StartUpEngines) Intent() Intent {
+	return IntentStartUp
+}
 
+func (m *StartUpEngines) Facilities() []atcmodel.Facility {
+	return startUpFacilities
+}
+
+func (m *StartUpEngines) reply(messageOut chan message.OutgoingMessage, text string) {
 	messageOut <- message.OutgoingMessage{
-		Message: message.FromMessage(m.Message.Context, m.Message, messageText),
-		Model:   "aura-asteria-en",
+		Message: message.FromMessage(m.Message.Context, m.Message, text),
+	}
+}
+
+func (m *StartUpEngines) Execute(atc *atcmodel.AtcModel, messageOut chan message.OutgoingMessage) error {
+	fmt.Printf("[execute] start up engines %s", m.Message.ClientName)
+
+	callsign, leaderId, found := resolveCaller(atc, m.Message)
+	if !found {
+		m.reply(messageOut, fmt.Sprintf("%s, unable to locate your aircraft, say again", callsign))
+		return fmt.Errorf("no plane found for %s", callsign)
+	}
+
+	leader := atc.AllPlaneData[leaderId]
+	if atc.PlaneState(leaderId).State().IsAirborne() {
+		m.reply(messageOut, fmt.Sprintf("%s, you are airborne, unable startup", callsign))
+		return nil
+	}
+
+	planeTypes := m.Squadron.PlaneTypes
+	if len(planeTypes) == 0 {
+		planeTypes = []string{leader.Labels.ACMIName}
+	}
+
+	wingmen, err := m.findWingmen(atc, leaderId, planeTypes)
+	if err != nil {
+		return err
+	}
+	if len(wingmen)+1 < m.Squadron.FlightSize {
+		log.Warn().Msgf("%s asked for a flight of %d but only %d wingmen found", callsign, m.Squadron.FlightSize, len(wingmen))
+	}
+	atc.CreateSquad(leaderId, wingmen, m.radio())
+
+	airfield, _ := atc.Map.NearestAirfield(leader.Frame.Point)
+	if airfield == nil {
+		return fmt.Errorf("no airfields loaded")
+	}
+	runway := airfield.ActiveRunway()
+	if runway == nil {
+		return fmt.Errorf("no runways at %s", airfield.Name)
 	}
+	weather := atc.WeatherAt(airfield)
+
+	messageText := fmt.Sprintf("%s, startup approved", callsign)
+	if flightSize := len(wingmen) + 1; flightSize > 1 {
+		messageText = fmt.Sprintf("%s for flight of %s", messageText, atcmodel.SpokenNumber(flightSize))
+	}
+	messageText = fmt.Sprintf("%s, %s, %s, runway %s in use", messageText, weather.WindPhrase(atc.Declination(airfield)),
+		weather.AltimeterPhrase(), runway.Designator)
+
+	m.reply(messageOut, messageText)
 
 	return nil
 }
 
-func (p *StartUpEnginesParser) ParseSquadronInfo(globalContext *GlobalCommandContext, message *message.Message[string]) PlayerCommand {
-}
+// findWingmen picks the closest planes of the right type on the ground near the leader
+func (m *StartUpEngines) findWingmen(atc *atcmodel.AtcModel, leaderId uint64, planeTypes []string) ([]uint64, error) {
+	wingmen := []uint64{}
+	if m.Squadron.FlightSize <= 1 {
+		return wingmen, nil
+	}
 
-func (p *StartUpEnginesParser) Parse(globalContext *GlobalCommandContext, message *message.Message[string]) PlayerCommand {
-	keywords := []string{"startup", "start up", "engines", "engine"}
-	for _, keyword := range keywords {
-		if strings.Contains(strings.ToLower(message.Data), keyword) {
-			return &StartUpEngines{
-				globalContext: globalContext,
-				Message:       message,
-			}
+	candidates, err := atc.FindCandidatesForSquad(m.Message.Context, leaderId, planeTypes, SQUAD_SEARCH_DISTANCE)
+	if err != nil {
+		return nil, err
+	}
+
+	leaderSquad := atc.PlaneToSquad[leaderId]
+	for _, candidate := range candidates {
+		if len(wingmen)+1 >= m.Squadron.FlightSize {
+			break
+		}
+		if candidate.AGL > atcmodel.IS_AIRBORN_AGL {
+			continue
+		}
+		// don't steal wingmen from other flights
+		if candidate.IsAlreadyInSquad && atc.PlaneToSquad[candidate.PlaneId] != leaderSquad {
+			continue
 		}
+		wingmen = append(wingmen, candidate.PlaneId)
 	}
-	return nil
+	return wingmen, nil
+}
+
+func (m *StartUpEngines) radio() types.Radio {
+	if len(m.Message.Frequencies) == 0 {
+		return types.Radio{}
+	}
+	return types.Radio{
+		Frequency:  m.Message.Frequencies[0].Frequency,
+		Modulation: types.Modulation(m.Message.Frequencies[0].Modulation),
+	}
+}
+
+// ParseSquadronInfo pulls the flight size, aircraft type and parking spots out of a startup request, e.g.
+// "flight of two a-10s at parking 86 and 87"
+func (p *StartUpEnginesParser) ParseSquadronInfo(text string) SquadronInfo {
+	call := ParseRadioCall(text)
+	return squadronInfo(&call)
+}
+
+func squadronInfo(call *RadioCall) SquadronInfo {
+	info := SquadronInfo{FlightSize: 1, PlaneTypes: call.PlaneTypes, Parking: call.Parking}
+	if call.FlightSize > 0 {
+		info.FlightSize = call.FlightSize
+	} else if len(call.Parking) > 1 {
+		// "hawg 3 1 at parking 86 and 87" is a flight of two
+		info.FlightSize = len(call.Parking)
+	}
+	return info
+}
+
+func (p *StartUpEnginesParser) Parse(globalContext *GlobalCommandContext, message *message.Message[string], call *RadioCall) (PlayerCommand, float64) {
+	confidence := scoreCall(call, IntentStartUp, startUpFacilities...)
+	if confidence == 0 {
+		return nil, 0
+	}
+	return &StartUpEngines{
+		globalContext: globalContext,
+		Message:       message,
+		Squadron:      squadronInfo(call),
+	}, confidence
 }
@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/dharmab/skyeye/pkg/trackfiles"
+	"github.com/martinlindhe/unit"
+)
+
+func TestParseSquadronInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected SquadronInfo
+	}{
+		{
+			name:  "full request",
+			input: "anapa tower, hawg 3-1, flight of two a-10s at parking position 86 and 87, requesting startup and weather information",
+			expected: SquadronInfo{
+				FlightSize: 2,
+				PlaneTypes: []string{"A-10C_2", "A-10C", "A-10A"},
+				Parking:    []string{"86", "87"},
+			},
+		},
+		{
+			name:  "digits and ship",
+			input: "tower viper 1 1 4-ship f-16 parking spots 12, 13, 14 and 15 request startup",
+			expected: SquadronInfo{
+				FlightSize: 4,
+				PlaneTypes: []string{"F-16C_50"},
+				Parking:    []string{"12", "13", "14", "15"},
+			},
+		},
+		{
+			name:  "flight size from parking",
+			input: "anapa ground hawg 3 1 at parking 86 and 87 request startup",
+			expected: SquadronInfo{
+				FlightSize: 2,
+				PlaneTypes: []string{},
+				Parking:    []string{"86", "87"},
+			},
+		},
+		{
+			name:  "single ship",
+			input: "tower enfield 1 1 request engine start",
+			expected: SquadronInfo{
+				FlightSize: 1,
+				PlaneTypes: []string{},
+				Parking:    []string{},
+			},
+		},
+	}
+
+	parser := &StartUpEnginesParser{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parser.ParseSquadronInfo(tt.input))
+		})
+	}
+}
+
+func TestStartUpEngines(t *testing.T) {
+	atcMap, err := atcmodel.LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	anapa := atcMap.AirfieldByName("Anapa")
+
+	model := atcmodel.NewAtcModel(atcMap)
+	parking := pointFrom(anapa.Position, 132*unit.Degree, 400*unit.Meter)
+	addPlane(model, 1, "hawg31", trackfiles.Frame{Point: parking, AGL: ptrlength(0)})
+	addPlane(model, 2, "hawg32", trackfiles.Frame{Point: pointFrom(parking, 42*unit.Degree, 30*unit.Meter), AGL: ptrlength(0)})
+	addPlane(model, 3, "hawg33", trackfiles.Frame{Point: pointFrom(parking, 42*unit.Degree, 60*unit.Meter), AGL: ptrlength(0)})
+	addPlane(model, 4, "viper11", trackfiles.Frame{Point: pointFrom(parking, 222*unit.Degree, 20*unit.Meter), AGL: ptrlength(0)})
+	model.AllPlaneData[4].Labels.ACMIName = "F-16C_50"
+
+	cp := NewCommandProcessor(&MockGenerator{})
+	cp.RegisterParser(&StartUpEnginesParser{})
+
+	msg := message.Message[string]{
+		Context:    context.Background(),
+		TraceId:    "trace1",
+		ClientName: "hawg31",
+		Data:       "Anapa tower, Hawg 3-1, flight of two A-10s at parking 86 and 87, requesting startup",
+	}
+	result, err := cp.ProcessText(context.Background(), &msg)
+	assert.Nil(t, err)
+
+	outChan := make(chan message.OutgoingMessage, 10)
+	assert.Nil(t, result.ParsedCommand.Execute(model, outChan))
+
+	response := <-outChan
+	assert.Equal(t, "hawg 3 1, startup approved for flight of two, wind calm, altimeter 2992, runway 04 in use", response.Message.Data)
+
+	squad, ok := model.PlaneToSquad[1]
+	assert.True(t, ok, "leader should be in a squad")
+	assert.Equal(t, uint64(1), squad.LeaderId)
+	assert.Len(t, squad.PlaneStates, 2)
+	assert.Contains(t, squad.PlaneStates, uint64(2), "closest A-10 should be the wingman")
+	assert.Same(t, model.PlaneToSquad[2], squad)
+	assert.NotContains(t, model.PlaneToSquad, uint64(4))
+
+	// unknown callers are told to try again
+	msg = message.Message[string]{Context: context.Background(), ClientName: "nobody", Data: "request startup"}
+	result, err = cp.ProcessText(context.Background(), &msg)
+	assert.Nil(t, err)
+	assert.NotNil(t, result.ParsedCommand.Execute(model, outChan))
+	response = <-outChan
+	assert.Equal(t, "nobody, unable to locate your aircraft, say again", response.Message.Data)
+}
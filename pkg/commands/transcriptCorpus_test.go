@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/message"
+	"github.com/stretchr/testify/assert"
+)
+
+// Every file in testdata/transcripts is a list of transcripts, as speech recognition hands them to us, and what
+// the command processor should make of them. Add the calls that trip the parsers up here, mishearings and all.
+// Slots left out aren't checked.
+type transcriptCase struct {
+	Transcript string `json:"transcript"`
+	// String() of the command, "" if there shouldn't be one
+	Command   string `json:"command"`
+	Intent    string `json:"intent"`
+	Addressee string `json:"addressee"`
+	Facility  string `json:"facility"`
+	// as the controller says it, e.g. "hawg 3 1"
+	Callsign     string   `json:"callsign"`
+	FlightSize   int      `json:"flight_size"`
+	PlaneTypes   []string `json:"plane_types"`
+	Parking      []string `json:"parking"`
+	Runway       string   `json:"runway"`
+	AltitudeFeet float64  `json:"altitude_feet"`
+	Heading      float64  `json:"heading"`
+	// why the case is here, not checked
+	Note string `json:"note"`
+}
+
+func TestTranscriptCorpus(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "transcripts", "*.json"))
+	assert.Nil(t, err)
+	assert.NotEmpty(t, files)
+
+	cp := NewCommandProcessor(&MockGenerator{})
+	cp.RegisterParser(&RadioCheckParser{})
+	cp.RegisterParser(&RequestTaxiParser{})
+	cp.RegisterParser(&StartUpEnginesParser{})
+	cp.RegisterParser(&RequestTakeoffParser{})
+	cp.RegisterParser(&InboundForLandingParser{})
+	cp.RegisterParser(&RepeatLastCallParser{})
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		assert.Nil(t, err)
+		var cases []transcriptCase
+		if !assert.Nil(t, json.Unmarshal(data, &cases), file) {
+			continue
+		}
+
+		for _, tt := range cases {
+			t.Run(filepath.Base(file)+"/"+tt.Transcript, func(t *testing.T) {
+				msg := message.Message[string]{Context: context.Background(), ClientName: "player", Data: tt.Transcript}
+				result, _ := cp.ProcessText(context.Background(), &msg)
+
+				command := ""
+				if result.ParsedCommand != nil {
+					command = result.ParsedCommand.(interface{ String() string }).String()
+				}
+				assert.Equal(t, tt.Command, command, tt.Note)
+
+				call := result.Call
+				if tt.Intent != "" {
+					assert.Equal(t, tt.Intent, call.Intent.String())
+				}
+				assert.Equal(t, tt.Addressee, call.Addressee)
+				assert.Equal(t, tt.Facility, call.Facility)
+				if tt.Callsign != "" {
+					assert.True(t, call.HasCallsign)
+					assert.Equal(t, tt.Callsign, call.Callsign.String())
+				}
+				if tt.FlightSize != 0 {
+					assert.Equal(t, tt.FlightSize, call.FlightSize)
+				}
+				if tt.PlaneTypes != nil {
+					assert.Equal(t, tt.PlaneTypes, call.PlaneTypes)
+				}
+				if tt.Parking != nil {
+					assert.Equal(t, tt.Parking, call.Parking)
+				}
+				if tt.Runway != "" {
+					assert.Equal(t, tt.Runway, call.Runway)
+				}
+				if tt.AltitudeFeet != 0 && assert.NotNil(t, call.Altitude) {
+					assert.InDelta(t, tt.AltitudeFeet, call.Altitude.Feet(), 1)
+				}
+				if tt.Heading != 0 && assert.NotNil(t, call.Heading) {
+					assert.InDelta(t, tt.Heading, call.Heading.Degrees(), 0.5)
+				}
+			})
+		}
+	}
+}
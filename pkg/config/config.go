@@ -0,0 +1,440 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	deepgramspeaker "github.com/ErikGoldman/DCSAtcOverhaul/pkg/deepgramSpeaker"
+	piperspeaker "github.com/ErikGoldman/DCSAtcOverhaul/pkg/piperSpeaker"
+	"github.com/dharmab/skyeye/pkg/coalitions"
+	"github.com/dharmab/skyeye/pkg/simpleradio/voice"
+	"github.com/martinlindhe/unit"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is everything dcs-atc needs to run, read from a YAML or JSON file. Any setting can be overridden with
+// an environment variable named after its path, e.g. DCS_ATC_SRS_PASSWORD or DCS_ATC_DEEPGRAM_API_KEY, which
+// keeps secrets out of the file.
+type Config struct {
+	SRS       SRSConfig       `yaml:"srs"`
+	Telemetry TelemetryConfig `yaml:"telemetry"`
+
+	// bundled DCS terrain name, e.g. "caucasus", or a path to an airfield data file
+	Terrain string `yaml:"terrain"`
+	// .miz (or extracted mission file) to read wind, QNH, temperature and clouds from
+	MissionFile string `yaml:"mission_file"`
+
+	// airfield controllers to staff
+	Facilities []FacilityConfig `yaml:"facilities"`
+	// other frequencies to listen on, each gets a controller that answers anything
+	Frequencies []float64 `yaml:"frequencies_mhz"`
+	// which of the frequencies above and the facilities' to work in FM rather than AM
+	FMFrequencies []float64 `yaml:"fm_frequencies_mhz"`
+	// text to speech voices handed out in turn to controllers without their own, by default every voice the
+	// speech synthesizer has
+	Voices []string `yaml:"voices"`
+
+	// "deepgram" or "whisper"
+	Recognizer string `yaml:"recognizer"`
+	// "deepgram" or "piper"
+	SpeechSynthesizer string         `yaml:"speech_synthesizer"`
+	Deepgram          DeepgramConfig `yaml:"deepgram"`
+	Whisper           WhisperConfig  `yaml:"whisper"`
+	Piper             PiperConfig    `yaml:"piper"`
+	LLM               LLMConfig      `yaml:"llm"`
+
+	SpeechCache SpeechCacheConfig `yaml:"speech_cache"`
+	Transmit    TransmitConfig    `yaml:"transmit"`
+	Workers     WorkersConfig     `yaml:"workers"`
+	Logging     LoggingConfig     `yaml:"logging"`
+}
+
+type SRSConfig struct {
+	// host:port of the SRS server
+	Address string `yaml:"address"`
+	// external AWACS mode password for the coalition
+	Password   string `yaml:"password"`
+	ClientName string `yaml:"client_name"`
+	// "blue" or "red"
+	Coalition string `yaml:"coalition"`
+}
+
+type TelemetryConfig struct {
+	// host:port of the Tacview real-time telemetry server
+	Address  string `yaml:"address"`
+	Password string `yaml:"password"`
+	// how we introduce ourselves to the server
+	Hostname string `yaml:"hostname"`
+}
+
+// FacilityConfig staffs a controller at an airfield, e.g. Anapa ground
+type FacilityConfig struct {
+	Airfield string `yaml:"airfield"`
+	// "ground", "tower", "approach", "departure" or "atis"
+	Facility string `yaml:"facility"`
+	// defaults to the airfield's frequencies for the facility
+	Frequencies []float64 `yaml:"frequencies_mhz"`
+	// defaults to the next of the shared voices
+	Voice string `yaml:"voice"`
+}
+
+type DeepgramConfig struct {
+	APIKey string `yaml:"api_key"`
+}
+
+type WhisperConfig struct {
+	BinaryPath string `yaml:"binary_path"`
+	ModelPath  string `yaml:"model_path"`
+	Threads    int    `yaml:"threads"`
+}
+
+type PiperConfig struct {
+	BinaryPath   string `yaml:"binary_path"`
+	VoicesDir    string `yaml:"voices_dir"`
+	DefaultVoice string `yaml:"default_voice"`
+}
+
+// LLMConfig points at an OpenAI-compatible chat endpoint to classify calls with, e.g. "http://localhost:8080/v1"
+// for a local llama.cpp server. The rule-based parsers are used when it's not set or not responding.
+type LLMConfig struct {
+	Endpoint       string `yaml:"endpoint"`
+	Model          string `yaml:"model"`
+	APIKey         string `yaml:"api_key"`
+	TimeoutSeconds int    `yaml:"timeout_seconds"`
+}
+
+// SpeechCacheConfig keeps synthesized phrases on disk so they're only paid for once. The least recently used
+// are thrown away past MaxMegabytes. Leave Dir empty to synthesize every reply fresh.
+type SpeechCacheConfig struct {
+	Dir          string  `yaml:"dir"`
+	MaxMegabytes float64 `yaml:"max_megabytes"`
+	// synthesize common phrases and controller names at startup
+	Prewarm bool `yaml:"prewarm"`
+}
+
+// TransmitConfig holds replies until nobody has transmitted on the frequency for QuietGapSeconds, so we don't
+// step on a readback or the next call, but no longer than MaxDelaySeconds. Safety calls don't wait.
+type TransmitConfig struct {
+	QuietGapSeconds float64 `yaml:"quiet_gap_seconds"`
+	MaxDelaySeconds float64 `yaml:"max_delay_seconds"`
+}
+
+// WorkersConfig is how many transmissions are recognized, parsed and spoken at once across all frequencies
+type WorkersConfig struct {
+	Recognition int `yaml:"recognition"`
+	Parsing     int `yaml:"parsing"`
+	Synthesis   int `yaml:"synthesis"`
+}
+
+type LoggingConfig struct {
+	// "trace", "debug", "info", "warn" or "error"
+	Level string `yaml:"level"`
+	// "json" or "console"
+	Format string `yaml:"format"`
+	// log every transcript
+	Transcripts bool `yaml:"transcripts"`
+}
+
+// prefix for environment variable overrides
+const ENV_PREFIX = "DCS_ATC_"
+
+// Default is the configuration before the file and environment are applied
+func Default() Config {
+	return Config{
+		SRS: SRSConfig{
+			Address:    "localhost:5002",
+			ClientName: "ATC",
+			Coalition:  "blue",
+		},
+		Telemetry: TelemetryConfig{
+			Address:  "localhost:42674",
+			Hostname: "dcs-atc",
+		},
+		Recognizer:        "deepgram",
+		SpeechSynthesizer: "deepgram",
+		Whisper:           WhisperConfig{BinaryPath: "third_party/whisper.cpp/main"},
+		Piper:             PiperConfig{BinaryPath: "piper"},
+		SpeechCache:       SpeechCacheConfig{Dir: "speech_cache", MaxMegabytes: 256, Prewarm: true},
+		Transmit:          TransmitConfig{QuietGapSeconds: 1, MaxDelaySeconds: 10},
+		Workers:           WorkersConfig{Recognition: 4, Parsing: 4, Synthesis: 2},
+		Logging:           LoggingConfig{Level: "info", Format: "json", Transcripts: true},
+	}
+}
+
+// the one frequency dcs-atc listened on before it was configurable
+const LEGACY_FREQUENCY_MHZ = 305.0
+
+// Load reads the configuration file, YAML or JSON, applies environment overrides and validates the result
+func Load(path string) (Config, error) {
+	return load(path, Default())
+}
+
+// LoadLegacy reads the config.json older installs have, which only held the Deepgram key. Those listened on
+// LEGACY_FREQUENCY_MHZ alone, so that's the default for anything the file doesn't say.
+func LoadLegacy(path string) (Config, error) {
+	config := Default()
+	config.Frequencies = []float64{LEGACY_FREQUENCY_MHZ}
+	return load(path, config)
+}
+
+func load(path string, config Config) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("error reading config %s: %w", path, err)
+	}
+	// JSON is YAML, so one decoder reads both
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("error parsing config %s: %w", filepath.Base(path), err)
+	}
+	if err := applyEnv(reflect.ValueOf(&config).Elem(), ENV_PREFIX, os.LookupEnv); err != nil {
+		return Config{}, err
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid config %s:\n%w", path, err)
+	}
+	return config, nil
+}
+
+// Validate reports every problem with the configuration at once
+func (c *Config) Validate() error {
+	problems := []error{}
+	problem := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if c.SRS.Address == "" {
+		problem("srs.address is required")
+	}
+	if _, err := c.Coalition(); err != nil {
+		problem("srs.coalition: %w", err)
+	}
+	if c.Telemetry.Address == "" {
+		problem("telemetry.address is required")
+	}
+	if len(c.Facilities) == 0 && len(c.Frequencies) == 0 {
+		problem("at least one of facilities or frequencies_mhz is required")
+	}
+	for i, facility := range c.Facilities {
+		if facility.Airfield == "" {
+			problem("facilities[%d].airfield is required", i)
+		}
+		if atcmodel.ParseFacility(facility.Facility) == atcmodel.FacilityUnknown {
+			problem("facilities[%d].facility: unknown facility %q", i, facility.Facility)
+		}
+		for _, mhz := range facility.Frequencies {
+			if mhz <= 0 {
+				problem("facilities[%d].frequencies_mhz: %v is not a frequency", i, mhz)
+			}
+		}
+	}
+	for _, mhz := range c.Frequencies {
+		if mhz <= 0 {
+			problem("frequencies_mhz: %v is not a frequency", mhz)
+		}
+	}
+
+	switch c.Recognizer {
+	case "deepgram":
+		if c.Deepgram.APIKey == "" {
+			problem("deepgram.api_key is required for the deepgram recognizer")
+		}
+	case "whisper":
+		if c.Whisper.ModelPath == "" {
+			problem("whisper.model_path is required for the whisper recognizer")
+		}
+	default:
+		problem("recognizer: unknown recognizer %q", c.Recognizer)
+	}
+	switch c.SpeechSynthesizer {
+	case "deepgram":
+		if c.Deepgram.APIKey == "" {
+			problem("deepgram.api_key is required for the deepgram speech synthesizer")
+		}
+	case "piper":
+		if c.Piper.VoicesDir == "" || c.Piper.DefaultVoice == "" {
+			problem("piper.voices_dir and piper.default_voice are required for the piper speech synthesizer")
+		}
+	default:
+		problem("speech_synthesizer: unknown speech synthesizer %q", c.SpeechSynthesizer)
+	}
+
+	if c.LLM.Endpoint != "" && c.LLM.Model == "" {
+		problem("llm.model is required with llm.endpoint")
+	}
+	if c.SpeechCache.Dir != "" && c.SpeechCache.MaxMegabytes <= 0 {
+		problem("speech_cache.max_megabytes must be more than 0")
+	}
+	if c.Transmit.QuietGapSeconds < 0 || c.Transmit.MaxDelaySeconds < 0 {
+		problem("transmit: quiet_gap_seconds and max_delay_seconds can't be negative")
+	}
+	if c.Workers.Recognition <= 0 || c.Workers.Parsing <= 0 || c.Workers.Synthesis <= 0 {
+		problem("workers: recognition, parsing and synthesis all need at least one worker")
+	}
+	if _, err := zerolog.ParseLevel(c.Logging.Level); err != nil || c.Logging.Level == "" {
+		problem("logging.level: unknown level %q", c.Logging.Level)
+	}
+	if c.Logging.Format != "json" && c.Logging.Format != "console" {
+		problem("logging.format: must be json or console, not %q", c.Logging.Format)
+	}
+
+	return errors.Join(problems...)
+}
+
+// Coalition is the SRS coalition to join
+func (c *Config) Coalition() (coalitions.Coalition, error) {
+	switch strings.ToLower(c.SRS.Coalition) {
+	case "blue":
+		return coalitions.Blue, nil
+	case "red":
+		return coalitions.Red, nil
+	default:
+		return 0, fmt.Errorf("must be blue or red, not %q", c.SRS.Coalition)
+	}
+}
+
+// StaffControllers puts a controller on each configured facility and frequency, and returns every frequency to
+// listen on. Facilities given their own frequencies use them instead of the airfield's usual ones.
+func (c *Config) StaffControllers(model *atcmodel.AtcModel) ([]unit.Frequency, error) {
+	frequencies := []unit.Frequency{}
+	for i, facilityConfig := range c.Facilities {
+		airfield := model.Map.AirfieldByName(facilityConfig.Airfield)
+		if airfield == nil {
+			airfield = model.Map.AirfieldBySpokenName(facilityConfig.Airfield)
+		}
+		if airfield == nil {
+			return nil, fmt.Errorf("facilities[%d]: no airfield called %s on %s", i, facilityConfig.Airfield, model.Map.Terrain)
+		}
+		facility := atcmodel.ParseFacility(facilityConfig.Facility)
+		if len(facilityConfig.Frequencies) > 0 {
+			airfield.SetFacilityFrequencies(facility, toFrequencies(facilityConfig.Frequencies))
+		}
+		if len(airfield.FacilityFrequencies(facility)) == 0 {
+			return nil, fmt.Errorf("facilities[%d]: %s has no %s frequency, set frequencies_mhz", i, airfield.Name, facility)
+		}
+		frequencies = append(frequencies, airfield.FacilityFrequencies(facility)...)
+	}
+	frequencies = append(frequencies, toFrequencies(c.Frequencies)...)
+
+	model.StaffControllers(frequencies, c.voices())
+	model.FMFrequencies = toFrequencies(c.FMFrequencies)
+	for _, frequency := range model.FMFrequencies {
+		if model.ControllerOn([]voice.Frequency{{Frequency: frequency.Hertz()}}) == nil {
+			return nil, fmt.Errorf("fm_frequencies_mhz: not listening on %s", atcmodel.FrequencyPhrase(frequency))
+		}
+	}
+	for _, facilityConfig := range c.Facilities {
+		if facilityConfig.Voice == "" {
+			continue
+		}
+		airfield := model.Map.AirfieldByName(facilityConfig.Airfield)
+		if airfield == nil {
+			airfield = model.Map.AirfieldBySpokenName(facilityConfig.Airfield)
+		}
+		if controller := model.ControllerFor(airfield, atcmodel.ParseFacility(facilityConfig.Facility)); controller != nil {
+			controller.Voice = facilityConfig.Voice
+		}
+	}
+	return frequencies, nil
+}
+
+// voices are the configured voices, or every voice the speech synthesizer has
+func (c *Config) voices() []string {
+	if len(c.Voices) > 0 {
+		return c.Voices
+	}
+	switch c.SpeechSynthesizer {
+	case "deepgram":
+		return deepgramspeaker.VOICES
+	case "piper":
+		return piperspeaker.InstalledVoices(c.Piper.VoicesDir, c.Piper.DefaultVoice)
+	}
+	return nil
+}
+
+func toFrequencies(mhz []float64) []unit.Frequency {
+	frequencies := make([]unit.Frequency, 0, len(mhz))
+	for _, f := range mhz {
+		frequencies = append(frequencies, unit.Frequency(f)*unit.Megahertz)
+	}
+	return frequencies
+}
+
+// applyEnv overrides fields from environment variables named after their path, e.g. DCS_ATC_SRS_ADDRESS. Lists
+// are comma separated. Entries of lists of sections, like facilities, can't be overridden.
+func applyEnv(value reflect.Value, prefix string, lookup func(string) (string, bool)) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		name := prefix + strings.ToUpper(strings.Split(value.Type().Field(i).Tag.Get("yaml"), ",")[0])
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field, name+"_", lookup); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Struct {
+			continue
+		}
+		env, ok := lookup(name)
+		if !ok {
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.Slice:
+			items := []string{}
+			for _, item := range strings.Split(env, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			list := reflect.MakeSlice(field.Type(), len(items), len(items))
+			for j, item := range items {
+				if err := setScalar(list.Index(j), item); err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+			}
+			field.Set(list)
+		default:
+			if err := setScalar(field, env); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func setScalar(field reflect.Value, text string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(text)
+	case reflect.Int:
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", text)
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", text)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", text)
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("can't be set from the environment")
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	deepgramspeaker "github.com/ErikGoldman/DCSAtcOverhaul/pkg/deepgramSpeaker"
+	"github.com/dharmab/skyeye/pkg/coalitions"
+	"github.com/dharmab/skyeye/pkg/simpleradio/types"
+	"github.com/martinlindhe/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, name string, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(path, []byte(contents), 0o600))
+	return path
+}
+
+func TestLoad_Example(t *testing.T) {
+	t.Setenv("DCS_ATC_DEEPGRAM_API_KEY", "key")
+	config, err := Load(filepath.Join("..", "..", "config.example.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost:5002", config.SRS.Address)
+	assert.Len(t, config.Facilities, 3)
+	assert.Equal(t, "aura-orion-en", config.Facilities[0].Voice)
+	assert.Equal(t, []float64{260.3, 131.25}, config.Facilities[2].Frequencies)
+}
+
+func TestLoad_JSON(t *testing.T) {
+	config, err := Load(filepath.Join("testdata", "config.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, "192.168.86.40:5002", config.SRS.Address)
+	assert.Equal(t, "secret", config.SRS.Password)
+	coalition, err := config.Coalition()
+	assert.Nil(t, err)
+	assert.Equal(t, coalitions.Coalition(coalitions.Red), coalition)
+	assert.Equal(t, []float64{305}, config.Frequencies)
+
+	// anything left out keeps its default
+	assert.Equal(t, "ATC", config.SRS.ClientName)
+	assert.Equal(t, "deepgram", config.Recognizer)
+	assert.Equal(t, "info", config.Logging.Level)
+	assert.True(t, config.Logging.Transcripts)
+}
+
+func TestLoadLegacy(t *testing.T) {
+	// all a config.json had before everything else was configurable
+	path := writeConfig(t, "config.json", `{"deepgram": {"api_key": "key"}}`)
+	_, err := Load(path)
+	assert.ErrorContains(t, err, "at least one of facilities or frequencies_mhz is required")
+
+	config, err := LoadLegacy(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "key", config.Deepgram.APIKey)
+	assert.Equal(t, []float64{LEGACY_FREQUENCY_MHZ}, config.Frequencies)
+
+	// a newer config.json still chooses its own
+	config, err = LoadLegacy(filepath.Join("testdata", "config.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, "caucasus", config.Terrain)
+}
+
+func TestLoad_EnvironmentOverrides(t *testing.T) {
+	t.Setenv("DCS_ATC_SRS_PASSWORD", "from-env")
+	t.Setenv("DCS_ATC_FREQUENCIES_MHZ", "251, 305.5")
+	t.Setenv("DCS_ATC_LLM_TIMEOUT_SECONDS", "10")
+	t.Setenv("DCS_ATC_LOGGING_TRANSCRIPTS", "false")
+
+	config, err := Load(filepath.Join("testdata", "config.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, "from-env", config.SRS.Password)
+	assert.Equal(t, []float64{251, 305.5}, config.Frequencies)
+	assert.Equal(t, 10, config.LLM.TimeoutSeconds)
+	assert.False(t, config.Logging.Transcripts)
+
+	t.Setenv("DCS_ATC_LLM_TIMEOUT_SECONDS", "soon")
+	_, err = Load(filepath.Join("testdata", "config.json"))
+	assert.ErrorContains(t, err, "DCS_ATC_LLM_TIMEOUT_SECONDS")
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+srs:
+  coalition: green
+facilities:
+  - airfield: Anapa
+    facility: kitchen
+recognizer: whisper
+logging:
+  format: xml
+`)
+	_, err := Load(path)
+	assert.NotNil(t, err)
+	// every problem is reported at once
+	assert.ErrorContains(t, err, "srs.coalition")
+	assert.ErrorContains(t, err, `facilities[0].facility: unknown facility "kitchen"`)
+	assert.ErrorContains(t, err, "whisper.model_path is required")
+	assert.ErrorContains(t, err, "deepgram.api_key is required for the deepgram speech synthesizer")
+	assert.ErrorContains(t, err, "logging.format")
+
+	_, err = Load(writeConfig(t, "config.yaml", "srs: [not, a, section]"))
+	assert.ErrorContains(t, err, "error parsing config")
+}
+
+func TestApplyEnv_Names(t *testing.T) {
+	// every setting that can be overridden, to catch a missing yaml tag
+	names := []string{}
+	config := Default()
+	assert.Nil(t, applyEnv(reflect.ValueOf(&config).Elem(), ENV_PREFIX, func(name string) (string, bool) {
+		names = append(names, name)
+		return "", false
+	}))
+	assert.Contains(t, names, "DCS_ATC_SRS_ADDRESS")
+	assert.Contains(t, names, "DCS_ATC_TELEMETRY_PASSWORD")
+	assert.Contains(t, names, "DCS_ATC_PIPER_VOICES_DIR")
+	assert.Contains(t, names, "DCS_ATC_VOICES")
+	assert.Contains(t, names, "DCS_ATC_SPEECH_CACHE_MAX_MEGABYTES")
+	assert.NotContains(t, names, "DCS_ATC_FACILITIES")
+}
+
+func TestStaffControllers(t *testing.T) {
+	atcMap, err := atcmodel.LoadTerrainMap("caucasus")
+	assert.Nil(t, err)
+	model := atcmodel.NewAtcModel(atcMap)
+
+	config := Default()
+	config.Facilities = []FacilityConfig{
+		{Airfield: "Anapa", Facility: "ground", Voice: "ground-voice", Frequencies: []float64{250.1, 121.05}},
+		{Airfield: "Anapa", Facility: "tower"},
+		{Airfield: "Krymsk", Facility: "ground", Frequencies: []float64{253.1}},
+	}
+	config.Frequencies = []float64{305, 31}
+	config.FMFrequencies = []float64{31}
+	config.Voices = []string{"shared-voice"}
+
+	frequencies, err := config.StaffControllers(model)
+	assert.Nil(t, err)
+	assert.Len(t, frequencies, 7)
+	assert.Len(t, model.Controllers, 5)
+	assert.Equal(t, "anapa ground", model.Controllers[0].Name())
+	assert.Equal(t, "ground-voice", model.Controllers[0].Voice)
+	assert.Equal(t, "shared-voice", model.Controllers[1].Voice)
+	assert.Equal(t, "krymsk ground", model.Controllers[2].Name(), "staffed on its own frequency")
+	assert.Equal(t, "atc", model.Controllers[3].Name())
+	assert.Equal(t, types.Modulation(types.ModulationFM), model.Modulation(31*unit.Megahertz))
+	assert.Equal(t, types.Modulation(types.ModulationAM), model.Modulation(305*unit.Megahertz))
+
+	// without shared voices each synthesizer hands out its own
+	config.Voices = nil
+	_, err = config.StaffControllers(model)
+	assert.Nil(t, err)
+	assert.Equal(t, deepgramspeaker.VOICES[1], model.Controllers[1].Voice)
+	config.SpeechSynthesizer = "piper"
+	config.Piper.DefaultVoice = "en_US-amy"
+	config.Piper.VoicesDir = t.TempDir()
+	_, err = config.StaffControllers(model)
+	assert.Nil(t, err)
+	assert.Equal(t, "en_US-amy", model.Controllers[1].Voice)
+
+	config.FMFrequencies = []float64{32}
+	_, err = config.StaffControllers(model)
+	assert.ErrorContains(t, err, "not listening on 32")
+	config.FMFrequencies = nil
+
+	config.Facilities = []FacilityConfig{{Airfield: "Krymsk", Facility: "approach"}}
+	_, err = config.StaffControllers(model)
+	assert.ErrorContains(t, err, "Krymsk has no approach frequency")
+
+	config.Facilities = []FacilityConfig{{Airfield: "Atlantis", Facility: "tower"}}
+	_, err = config.StaffControllers(model)
+	assert.ErrorContains(t, err, "no airfield called Atlantis")
+}
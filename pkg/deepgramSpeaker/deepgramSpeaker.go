@@ -12,6 +12,13 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
+// SAMPLE_RATE is the rate of the linear16 audio deepgram sends
+const SAMPLE_RATE = 24000
+
+// VOICES are deepgram's english voices, for controllers without one of their own
+var VOICES = []string{"aura-asteria-en", "aura-orion-en", "aura-luna-en", "aura-arcas-en", "aura-stella-en",
+	"aura-perseus-en", "aura-athena-en", "aura-helios-en"}
+
 type TextToSpeech interface {
 	GenerateSpeech(model string, text string, out chan []byte) error
 	Disconnect() error
@@ -91,7 +98,7 @@ func (d *DeepgramSpeakSynthesizer) GenerateSpeech(model string, text string, out
 	ttsOptions := &interfaces.WSSpeakOptions{
 		Model:      model,
 		Encoding:   "linear16",
-		SampleRate: 24000,
+		SampleRate: SAMPLE_RATE,
 	}
 
 	ctx := context.Background()
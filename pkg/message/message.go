@@ -2,6 +2,7 @@ package message
 
 import (
 	"context"
+	"time"
 
 	"github.com/dharmab/skyeye/pkg/simpleradio"
 	"github.com/dharmab/skyeye/pkg/simpleradio/voice"
@@ -14,11 +15,20 @@ type Message[T any] struct {
 	Data        T
 	Frequencies []voice.Frequency
 
+	// mission time when the message was sent, zero until we've had telemetry
 	GameTimeHour   int
 	GameTimeMinute int
 	GameTimeSecond int
 }
 
+// SetGameTime stamps the message with the mission time of day
+func (m *Message[T]) SetGameTime(t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	m.GameTimeHour, m.GameTimeMinute, m.GameTimeSecond = t.Clock()
+}
+
 func FromMessage[T any](ctx context.Context, msg *Message[T], data T) Message[T] {
 	return Message[T]{Context: ctx, TraceId: msg.TraceId,
 		Frequencies: msg.Frequencies, ClientName: msg.ClientName, Data: data,
@@ -26,12 +36,21 @@ func FromMessage[T any](ctx context.Context, msg *Message[T], data T) Message[T]
 		GameTimeSecond: msg.GameTimeSecond}
 }
 
-func FromTransmission[T any](ctx context.Context, transmission simpleradio.Transmission, data T) Message[T] {
-	return Message[T]{Context: ctx, TraceId: transmission.TraceID,
+func FromTransmission[T any](ctx context.Context, transmission simpleradio.Transmission, data T, gameTime time.Time) Message[T] {
+	msg := Message[T]{Context: ctx, TraceId: transmission.TraceID,
 		Frequencies: transmission.Frequencies, ClientName: transmission.ClientName, Data: data}
+	msg.SetGameTime(gameTime)
+	return msg
 }
 
 type OutgoingMessage struct {
 	Message Message[string]
-	Model   string
+	// text to speech voice, left to the controller making the call
+	Model    string
+	Priority Priority
+	// waiting calls are dropped once this passes, zero for DEFAULT_CALL_LIFETIME
+	Expires time.Time
+	// a newer call with the same key replaces this one if it hasn't gone out yet, e.g. a go around replaces a
+	// landing clearance. Empty for calls that don't go stale.
+	Key string
 }
@@ -0,0 +1,194 @@
+package message
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Priority decides which of the calls waiting for a frequency goes next
+type Priority int
+
+const (
+	// ATIS and other broadcasts nobody asked for
+	PriorityInformational Priority = iota - 1
+	// the zero value, most calls are clearances or replies to pilots
+	PriorityClearance
+	// calls like a go around, which also go out without waiting for the frequency to go quiet
+	PrioritySafety
+	PriorityEmergency
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityInformational:
+		return "informational"
+	case PriorityClearance:
+		return "clearance"
+	case PrioritySafety:
+		return "safety"
+	case PriorityEmergency:
+		return "emergency"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	// calls without their own expiry aren't worth making after this long
+	DEFAULT_CALL_LIFETIME = 30 * time.Second
+)
+
+// Outbox takes the calls controllers make. Send never blocks, so the model loop can't be held up by the radio.
+type Outbox interface {
+	Send(msg OutgoingMessage)
+}
+
+// OutgoingQueue holds the calls waiting for one frequency, highest priority first and oldest first within a
+// priority. Push never blocks: when the queue is full the least important call is dropped.
+type OutgoingQueue struct {
+	size int
+
+	mu    sync.Mutex
+	calls []queuedCall
+	count uint64
+	// signalled when a call is pushed
+	pushed chan struct{}
+}
+
+type queuedCall struct {
+	msg OutgoingMessage
+	// order the call was pushed in
+	number uint64
+}
+
+func NewOutgoingQueue(size int) *OutgoingQueue {
+	return &OutgoingQueue{
+		size:   max(size, 1),
+		pushed: make(chan struct{}, 1),
+	}
+}
+
+// Push queues the call, replacing any waiting call with the same key. A call never replaces a more important
+// one, so a go around can't be lost to the routine call that follows it.
+func (q *OutgoingQueue) Push(msg OutgoingMessage) {
+	if msg.Expires.IsZero() {
+		msg.Expires = time.Now().Add(DEFAULT_CALL_LIFETIME)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.removeExpired(time.Now())
+
+	if msg.Key != "" {
+		for i, call := range q.calls {
+			if call.msg.Key == msg.Key {
+				if call.msg.Priority > msg.Priority {
+					log.Info().Msgf("dropping %q, %s call %q is still waiting", msg.Message.Data, call.msg.Priority, call.msg.Message.Data)
+					return
+				}
+				log.Info().Msgf("replacing %q with %q", call.msg.Message.Data, msg.Message.Data)
+				q.calls = append(q.calls[:i], q.calls[i+1:]...)
+				break
+			}
+		}
+	}
+
+	if len(q.calls) >= q.size {
+		// the newest of the least important calls goes, unless the new call is even less important
+		drop := len(q.calls) - 1
+		for i, call := range q.calls {
+			if call.msg.Priority < q.calls[drop].msg.Priority ||
+				(call.msg.Priority == q.calls[drop].msg.Priority && call.number > q.calls[drop].number) {
+				drop = i
+			}
+		}
+		if msg.Priority <= q.calls[drop].msg.Priority {
+			log.Error().Msgf("dropping %s call to %s, queue is full: %s", msg.Priority, msg.Message.ClientName, msg.Message.Data)
+			return
+		}
+		dropped := q.calls[drop].msg
+		log.Error().Msgf("dropping %s call to %s, queue is full: %s", dropped.Priority, dropped.Message.ClientName, dropped.Message.Data)
+		q.calls = append(q.calls[:drop], q.calls[drop+1:]...)
+	}
+
+	q.count++
+	q.calls = append(q.calls, queuedCall{msg: msg, number: q.count})
+	select {
+	case q.pushed <- struct{}{}:
+	default:
+	}
+}
+
+// Pop waits for the next call to make, false if the context ended first
+func (q *OutgoingQueue) Pop(ctx context.Context) (OutgoingMessage, bool) {
+	for {
+		if msg, ok := q.next(); ok {
+			return msg, true
+		}
+		select {
+		case <-ctx.Done():
+			return OutgoingMessage{}, false
+		case <-q.pushed:
+		}
+	}
+}
+
+func (q *OutgoingQueue) next() (OutgoingMessage, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.removeExpired(time.Now())
+	if len(q.calls) == 0 {
+		return OutgoingMessage{}, false
+	}
+
+	best := 0
+	for i, call := range q.calls {
+		if call.msg.Priority > q.calls[best].msg.Priority {
+			best = i
+		}
+	}
+	msg := q.calls[best].msg
+	q.calls = append(q.calls[:best], q.calls[best+1:]...)
+	return msg, true
+}
+
+// Stale is true if a call taken from the queue shouldn't be made any more, because it has expired or a newer
+// call with the same key, and at least as important, is waiting
+func (q *OutgoingQueue) Stale(msg OutgoingMessage) bool {
+	if time.Now().After(msg.Expires) {
+		return true
+	}
+	if msg.Key == "" {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, call := range q.calls {
+		if call.msg.Key == msg.Key && call.msg.Priority >= msg.Priority {
+			return true
+		}
+	}
+	return false
+}
+
+// Len is the number of calls waiting
+func (q *OutgoingQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.calls)
+}
+
+func (q *OutgoingQueue) removeExpired(now time.Time) {
+	calls := q.calls[:0]
+	for _, call := range q.calls {
+		if now.After(call.msg.Expires) {
+			log.Info().Msgf("call to %s expired before it could be made: %s", call.msg.Message.ClientName, call.msg.Message.Data)
+			continue
+		}
+		calls = append(calls, call)
+	}
+	q.calls = calls
+}
@@ -0,0 +1,120 @@
+package message
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func call(text string, priority Priority, key string) OutgoingMessage {
+	return OutgoingMessage{Message: Message[string]{ClientName: "hawg31", Data: text}, Priority: priority, Key: key}
+}
+
+// popAll empties the queue without waiting
+func popAll(q *OutgoingQueue) []string {
+	texts := []string{}
+	for {
+		msg, ok := q.next()
+		if !ok {
+			return texts
+		}
+		texts = append(texts, msg.Message.Data)
+	}
+}
+
+func TestOutgoingQueue_Priority(t *testing.T) {
+	q := NewOutgoingQueue(10)
+	q.Push(call("atis", PriorityInformational, ""))
+	q.Push(call("taxi", PriorityClearance, ""))
+	q.Push(call("go around", PrioritySafety, ""))
+	q.Push(call("takeoff", PriorityClearance, ""))
+	q.Push(call("mayday", PriorityEmergency, ""))
+
+	assert.Equal(t, []string{"mayday", "go around", "taxi", "takeoff", "atis"}, popAll(q))
+}
+
+func TestOutgoingQueue_Key(t *testing.T) {
+	q := NewOutgoingQueue(10)
+	q.Push(call("cleared to land", PriorityClearance, "arrival-1"))
+	q.Push(call("taxi", PriorityClearance, ""))
+	q.Push(call("go around", PrioritySafety, "arrival-1"))
+
+	assert.Equal(t, []string{"go around", "taxi"}, popAll(q))
+}
+
+func TestOutgoingQueue_KeyKeepsMoreImportantCall(t *testing.T) {
+	q := NewOutgoingQueue(10)
+	q.Push(call("go around", PrioritySafety, "arrival-1"))
+	q.Push(call("number two", PriorityClearance, "arrival-1"))
+	assert.Equal(t, []string{"go around"}, popAll(q))
+
+	q.Push(call("go around", PrioritySafety, "arrival-1"))
+	goAround, _ := q.Pop(context.Background())
+	q.Push(call("report initial", PriorityClearance, "arrival-1"))
+	assert.False(t, q.Stale(goAround), "a routine call doesn't cancel a go around being spoken")
+}
+
+func TestOutgoingQueue_Expiry(t *testing.T) {
+	q := NewOutgoingQueue(10)
+	stale := call("cleared to land", PriorityClearance, "")
+	stale.Expires = time.Now().Add(-time.Second)
+	q.Push(stale)
+	q.Push(call("taxi", PriorityClearance, ""))
+
+	msg, ok := q.Pop(context.Background())
+	assert.True(t, ok)
+	assert.Equal(t, "taxi", msg.Message.Data)
+	assert.WithinDuration(t, time.Now().Add(DEFAULT_CALL_LIFETIME), msg.Expires, time.Second)
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestOutgoingQueue_Full(t *testing.T) {
+	q := NewOutgoingQueue(3)
+	q.Push(call("atis", PriorityInformational, ""))
+	q.Push(call("taxi", PriorityClearance, ""))
+	q.Push(call("takeoff", PriorityClearance, ""))
+
+	// pushes out the least important call
+	q.Push(call("go around", PrioritySafety, ""))
+	assert.Equal(t, 3, q.Len())
+	// and then the newest of the equally important ones
+	q.Push(call("mayday", PriorityEmergency, ""))
+	// but not for something less important
+	q.Push(call("radio check", PriorityClearance, ""))
+
+	assert.Equal(t, []string{"mayday", "go around", "taxi"}, popAll(q))
+}
+
+func TestOutgoingQueue_Stale(t *testing.T) {
+	q := NewOutgoingQueue(10)
+	q.Push(call("cleared to land", PriorityClearance, "arrival-1"))
+	q.Push(call("taxi", PriorityClearance, ""))
+
+	landing, _ := q.Pop(context.Background())
+	assert.False(t, q.Stale(landing))
+	q.Push(call("go around", PrioritySafety, "arrival-1"))
+	assert.True(t, q.Stale(landing), "superseded while it was being spoken")
+
+	goAround, _ := q.Pop(context.Background())
+	assert.Equal(t, "go around", goAround.Message.Data)
+	goAround.Expires = time.Now().Add(-time.Second)
+	assert.True(t, q.Stale(goAround))
+}
+
+func TestOutgoingQueue_PopWaits(t *testing.T) {
+	q := NewOutgoingQueue(10)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		q.Push(call("taxi", PriorityClearance, ""))
+	}()
+	msg, ok := q.Pop(context.Background())
+	assert.True(t, ok)
+	assert.Equal(t, "taxi", msg.Message.Data)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, ok = q.Pop(ctx)
+	assert.False(t, ok)
+}
@@ -0,0 +1,231 @@
+package mission
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DCS saves missions as a single Lua assignment of a table literal, e.g. `mission = { ["weather"] = { ... } }`.
+// This is just enough of a parser for that: strings, numbers, booleans, nil and nested tables. Tables come
+// back as map[string]any, with array entries keyed "1", "2", ...
+
+type luaParser struct {
+	src string
+	pos int
+}
+
+// parseLuaAssignment parses `name = value` and returns the name and value
+func parseLuaAssignment(src string) (string, any, error) {
+	p := &luaParser{src: src}
+	p.skipSpace()
+	name := p.identifier()
+	if name == "" {
+		return "", nil, p.errorf("expected variable name")
+	}
+	p.skipSpace()
+	if !p.consume('=') {
+		return "", nil, p.errorf("expected '=' after %s", name)
+	}
+	value, err := p.value()
+	if err != nil {
+		return "", nil, err
+	}
+	return name, value, nil
+}
+
+func (p *luaParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("lua parse error at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *luaParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch {
+		case unicode.IsSpace(rune(p.src[p.pos])):
+			p.pos++
+		case strings.HasPrefix(p.src[p.pos:], "--"):
+			// DCS writes "-- end of [...]" comments after tables
+			end := strings.IndexByte(p.src[p.pos:], '\n')
+			if end < 0 {
+				p.pos = len(p.src)
+			} else {
+				p.pos += end + 1
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *luaParser) consume(c byte) bool {
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *luaParser) identifier() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := rune(p.src[p.pos])
+		if c == '_' || unicode.IsLetter(c) || (p.pos > start && unicode.IsDigit(c)) {
+			p.pos++
+		} else {
+			break
+		}
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *luaParser) value() (any, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of input")
+	}
+
+	switch c := p.src[p.pos]; {
+	case c == '{':
+		return p.table()
+	case c == '"' || c == '\'':
+		return p.string()
+	case c == '-' || c == '.' || unicode.IsDigit(rune(c)):
+		return p.number()
+	}
+
+	word := p.identifier()
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "nil":
+		return nil, nil
+	}
+	return nil, p.errorf("unexpected %q", word)
+}
+
+func (p *luaParser) table() (map[string]any, error) {
+	p.pos++ // {
+	table := make(map[string]any)
+	index := 1
+
+	for {
+		p.skipSpace()
+		if p.consume('}') {
+			return table, nil
+		}
+
+		key := ""
+		if p.consume('[') {
+			keyValue, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			key = luaKey(keyValue)
+			p.skipSpace()
+			if !p.consume(']') {
+				return nil, p.errorf("expected ']'")
+			}
+			p.skipSpace()
+			if !p.consume('=') {
+				return nil, p.errorf("expected '=' after [%s]", key)
+			}
+		} else {
+			// either `name = value` or a positional value
+			start := p.pos
+			name := p.identifier()
+			p.skipSpace()
+			if name != "" && name != "true" && name != "false" && name != "nil" && p.consume('=') {
+				key = name
+			} else {
+				p.pos = start
+				key = strconv.Itoa(index)
+				index++
+			}
+		}
+
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		table[key] = value
+
+		p.skipSpace()
+		if !p.consume(',') && !p.consume(';') {
+			p.skipSpace()
+			if !p.consume('}') {
+				return nil, p.errorf("expected ',' or '}'")
+			}
+			return table, nil
+		}
+	}
+}
+
+func (p *luaParser) string() (string, error) {
+	quote := p.src[p.pos]
+	p.pos++
+
+	var builder strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+		switch c {
+		case quote:
+			return builder.String(), nil
+		case '\\':
+			if p.pos >= len(p.src) {
+				return "", p.errorf("unterminated string")
+			}
+			escaped := p.src[p.pos]
+			p.pos++
+			switch escaped {
+			case 'n':
+				builder.WriteByte('\n')
+			case 't':
+				builder.WriteByte('\t')
+			case '\n':
+				// DCS escapes newlines in multi-line strings like briefings
+				builder.WriteByte('\n')
+			default:
+				builder.WriteByte(escaped)
+			}
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *luaParser) number() (float64, error) {
+	start := p.pos
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if unicode.IsDigit(rune(c)) || c == '.' || c == 'e' || c == 'E' ||
+			((c == '-' || c == '+') && (p.src[p.pos-1] == 'e' || p.src[p.pos-1] == 'E')) {
+			p.pos++
+		} else {
+			break
+		}
+	}
+	number, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+	if err != nil {
+		return 0, p.errorf("bad number %q", p.src[start:p.pos])
+	}
+	return number, nil
+}
+
+func luaKey(key any) string {
+	switch k := key.(type) {
+	case float64:
+		return strconv.FormatFloat(k, 'f', -1, 64)
+	case string:
+		return k
+	default:
+		return fmt.Sprint(k)
+	}
+}
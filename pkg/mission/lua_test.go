@@ -0,0 +1,21 @@
+package mission
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLuaAssignment(t *testing.T) {
+	name, value, err := parseLuaAssignment(`config = { 1, "two", ['three'] = { x = -3.5e2 }, [4] = true; nil, -- comment
+	}`)
+	assert.Nil(t, err)
+	assert.Equal(t, "config", name)
+	assert.Equal(t, map[string]any{
+		"1":     1.0,
+		"2":     "two",
+		"three": map[string]any{"x": -350.0},
+		"4":     true,
+		"3":     nil,
+	}, value)
+}
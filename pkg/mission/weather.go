@@ -0,0 +1,157 @@
+package mission
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/dharmab/skyeye/pkg/bearings"
+	"github.com/martinlindhe/unit"
+)
+
+// the mission editor stores QNH in millimeters of mercury
+const MILLIMETER_OF_MERCURY = unit.Pascal * 133.322
+
+// LoadWeather reads the weather from a .miz, or from a mission file already extracted from one
+func LoadWeather(path string) (atcmodel.Weather, error) {
+	src, err := readMission(path)
+	if err != nil {
+		return atcmodel.Weather{}, err
+	}
+	return ParseWeather(src)
+}
+
+func readMission(path string) (string, error) {
+	if !strings.EqualFold(filepath.Ext(path), ".miz") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("error reading mission %s: %w", path, err)
+		}
+		return string(data), nil
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening mission %s: %w", path, err)
+	}
+	defer archive.Close()
+
+	file, err := archive.Open("mission")
+	if err != nil {
+		return "", fmt.Errorf("no mission file in %s: %w", path, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("error reading mission from %s: %w", path, err)
+	}
+	return string(data), nil
+}
+
+// ParseWeather pulls the sea level conditions out of the mission's weather table. Anything missing is left at
+// standard conditions.
+func ParseWeather(src string) (atcmodel.Weather, error) {
+	_, value, err := parseLuaAssignment(src)
+	if err != nil {
+		return atcmodel.Weather{}, err
+	}
+	mission, ok := value.(map[string]any)
+	if !ok {
+		return atcmodel.Weather{}, fmt.Errorf("mission is not a table")
+	}
+	table, ok := mission["weather"].(map[string]any)
+	if !ok {
+		return atcmodel.Weather{}, fmt.Errorf("mission has no weather")
+	}
+
+	weather := atcmodel.StandardWeather()
+
+	if speed, ok := number(table, "wind", "atGround", "speed"); ok {
+		weather.WindSpeed = unit.Speed(speed) * unit.MetersPerSecond
+	}
+	if direction, ok := number(table, "wind", "atGround", "dir"); ok {
+		// the mission stores where the wind is blowing to
+		weather.WindDirection = bearings.NewTrueBearing(unit.Angle(math.Mod(direction+180, 360)) * unit.Degree)
+	}
+	if qnh, ok := number(table, "qnh"); ok {
+		weather.QNH = unit.Pressure(qnh) * MILLIMETER_OF_MERCURY
+	}
+	if temperature, ok := number(table, "season", "temperature"); ok {
+		weather.Temperature = unit.FromCelsius(temperature)
+	}
+
+	if distance, ok := number(table, "visibility", "distance"); ok {
+		weather.Visibility = unit.Length(distance) * unit.Meter
+	}
+	if fog, _ := table["enable_fog"].(bool); fog {
+		if distance, ok := number(table, "fog", "visibility"); ok && distance > 0 &&
+			unit.Length(distance)*unit.Meter < weather.Visibility {
+			weather.Visibility = unit.Length(distance) * unit.Meter
+		}
+	}
+
+	if clouds, ok := table["clouds"].(map[string]any); ok {
+		weather.CloudCover = cloudCover(clouds)
+		if base, ok := number(clouds, "base"); ok {
+			weather.CloudBase = unit.Length(base) * unit.Meter
+		}
+	}
+
+	return weather, nil
+}
+
+// cloudCover maps the mission editor's cloud presets, or the old 0-10 density for missions without one
+func cloudCover(clouds map[string]any) atcmodel.CloudCover {
+	if preset, ok := clouds["preset"].(string); ok && preset != "" {
+		if strings.HasPrefix(preset, "Rainy") {
+			return atcmodel.CloudsOvercast
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(preset, "Preset"))
+		switch {
+		case err != nil:
+			return atcmodel.CloudsScattered
+		case n <= 3:
+			return atcmodel.CloudsFew
+		case n <= 12:
+			return atcmodel.CloudsScattered
+		case n <= 20:
+			return atcmodel.CloudsBroken
+		default:
+			return atcmodel.CloudsOvercast
+		}
+	}
+
+	density, _ := number(clouds, "density")
+	switch {
+	case density <= 0:
+		return atcmodel.CloudsClear
+	case density <= 2:
+		return atcmodel.CloudsFew
+	case density <= 5:
+		return atcmodel.CloudsScattered
+	case density <= 8:
+		return atcmodel.CloudsBroken
+	default:
+		return atcmodel.CloudsOvercast
+	}
+}
+
+// number looks up a number in nested tables, e.g. number(weather, "wind", "atGround", "speed")
+func number(table map[string]any, path ...string) (float64, bool) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := table[key].(map[string]any)
+		if !ok {
+			return 0, false
+		}
+		table = next
+	}
+	value, ok := table[path[len(path)-1]].(float64)
+	return value, ok
+}
@@ -0,0 +1,114 @@
+package mission
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ErikGoldman/DCSAtcOverhaul/pkg/atcmodel"
+	"github.com/martinlindhe/unit"
+	"github.com/stretchr/testify/assert"
+)
+
+// trimmed down from a mission saved by the DCS mission editor
+const testMission = `mission = 
+{
+    ["date"] = 
+    {
+        ["Day"] = 1,
+        ["Year"] = 2024,
+        ["Month"] = 6,
+    }, -- end of ["date"]
+    ["weather"] = 
+    {
+        ["atmosphere_type"] = 0,
+        ["wind"] = 
+        {
+            ["at8000"] = 
+            {
+                ["speed"] = 15,
+                ["dir"] = 60,
+            }, -- end of ["at8000"]
+            ["atGround"] = 
+            {
+                ["speed"] = 6,
+                ["dir"] = 40,
+            }, -- end of ["atGround"]
+        }, -- end of ["wind"]
+        ["enable_fog"] = true,
+        ["season"] = 
+        {
+            ["temperature"] = -4.5,
+        }, -- end of ["season"]
+        ["qnh"] = 755,
+        ["cyclones"] = 
+        {
+        }, -- end of ["cyclones"]
+        ["name"] = "Winter, clean sky",
+        ["fog"] = 
+        {
+            ["thickness"] = 100,
+            ["visibility"] = 3000,
+        }, -- end of ["fog"]
+        ["visibility"] = 
+        {
+            ["distance"] = 80000,
+        }, -- end of ["visibility"]
+        ["clouds"] = 
+        {
+            ["thickness"] = 200,
+            ["density"] = 0,
+            ["preset"] = "Preset14",
+            ["base"] = 1200,
+            ["iprecptns"] = 0,
+        }, -- end of ["clouds"]
+    }, -- end of ["weather"]
+    ["descriptionText"] = "Two ship \"CAS\"\
+out of Anapa",
+} -- end of mission
+`
+
+func TestParseWeather(t *testing.T) {
+	weather, err := ParseWeather(testMission)
+	assert.Nil(t, err)
+
+	// blowing towards 040 means it comes from 220
+	assert.InDelta(t, 220, weather.WindDirection.Degrees(), 0.01)
+	assert.InDelta(t, 11.7, weather.WindSpeed.Knots(), 0.1)
+	assert.Equal(t, "altimeter 2972", weather.AltimeterPhrase())
+	assert.InDelta(t, -4.5, weather.Temperature.Celsius(), 0.01)
+	assert.InDelta(t, 3000, weather.Visibility.Meters(), 0.01, "fog is worse than the visibility")
+	assert.Equal(t, atcmodel.CloudsBroken, weather.CloudCover)
+	assert.InDelta(t, 1200, weather.CloudBase.Meters(), 0.01)
+}
+
+func TestParseWeather_Defaults(t *testing.T) {
+	weather, err := ParseWeather(`mission = { ["weather"] = { ["clouds"] = { ["density"] = 4, ["base"] = 900 } } }`)
+	assert.Nil(t, err)
+	assert.Equal(t, "wind calm", weather.WindPhrase(0))
+	assert.Equal(t, "altimeter 2992", weather.AltimeterPhrase())
+	assert.Equal(t, atcmodel.CloudsScattered, weather.CloudCover)
+
+	_, err = ParseWeather(`mission = { ["date"] = {} }`)
+	assert.NotNil(t, err)
+	_, err = ParseWeather(`mission = { ["weather"] = { `)
+	assert.NotNil(t, err)
+}
+
+func TestLoadWeather_Miz(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.miz")
+	file, err := os.Create(path)
+	assert.Nil(t, err)
+	archive := zip.NewWriter(file)
+	entry, err := archive.Create("mission")
+	assert.Nil(t, err)
+	_, err = entry.Write([]byte(testMission))
+	assert.Nil(t, err)
+	assert.Nil(t, archive.Close())
+	assert.Nil(t, file.Close())
+
+	weather, err := LoadWeather(path)
+	assert.Nil(t, err)
+	assert.InDelta(t, (6 * unit.MetersPerSecond).Knots(), weather.WindSpeed.Knots(), 0.01)
+}
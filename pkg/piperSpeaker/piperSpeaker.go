@@ -0,0 +1,184 @@
+package piperspeaker
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SRS transmits 16kHz wideband audio, so resample whatever the voice produces to that
+const SAMPLE_RATE = 16000
+
+// bytes of linear16 audio per chunk sent to the out channel (100ms)
+const chunkSize = SAMPLE_RATE / 10 * 2
+
+// PiperSpeechSynthesizer implements deepgramspeaker.TextToSpeech by running a local piper binary
+// (https://github.com/rhasspy/piper), so controller transmissions work without a network connection.
+//
+// The model passed to GenerateSpeech is looked up as <voicesDir>/<model>.onnx; unknown models fall back
+// to the default voice.
+type PiperSpeechSynthesizer struct {
+	binaryPath   string
+	voicesDir    string
+	defaultVoice string
+
+	lock sync.Mutex
+	cmd  *exec.Cmd
+}
+
+func NewPiperSpeechSynthesizer(binaryPath string, voicesDir string, defaultVoice string) *PiperSpeechSynthesizer {
+	return &PiperSpeechSynthesizer{
+		binaryPath:   binaryPath,
+		voicesDir:    voicesDir,
+		defaultVoice: defaultVoice,
+	}
+}
+
+func (p *PiperSpeechSynthesizer) voicePath(model string) string {
+	path := filepath.Join(p.voicesDir, model+".onnx")
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	return filepath.Join(p.voicesDir, p.defaultVoice+".onnx")
+}
+
+// InstalledVoices are the voices in the directory that have their config next to them, the default voice first
+func InstalledVoices(voicesDir string, defaultVoice string) []string {
+	voices := []string{defaultVoice}
+	files, err := os.ReadDir(voicesDir)
+	if err != nil {
+		log.Warn().Err(err).Msgf("failed to list piper voices in %s", voicesDir)
+		return voices
+	}
+	for _, file := range files {
+		voice, ok := strings.CutSuffix(file.Name(), ".onnx")
+		if !ok || file.IsDir() || voice == defaultVoice {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(voicesDir, file.Name()+".json")); err == nil {
+			voices = append(voices, voice)
+		}
+	}
+	return voices
+}
+
+// piper writes raw audio at the voice's native rate, which is stored next to the model
+func readVoiceSampleRate(voicePath string) (int, error) {
+	configData, err := os.ReadFile(voicePath + ".json")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read voice config: %w", err)
+	}
+
+	var voiceConfig struct {
+		Audio struct {
+			SampleRate int `json:"sample_rate"`
+		} `json:"audio"`
+	}
+	if err := json.Unmarshal(configData, &voiceConfig); err != nil {
+		return 0, fmt.Errorf("failed to parse voice config: %w", err)
+	}
+	if voiceConfig.Audio.SampleRate <= 0 {
+		return 0, fmt.Errorf("voice config %s has no sample rate", voicePath+".json")
+	}
+	return voiceConfig.Audio.SampleRate, nil
+}
+
+func (p *PiperSpeechSynthesizer) GenerateSpeech(model string, text string, out chan []byte) error {
+	voicePath := p.voicePath(model)
+	sampleRate, err := readVoiceSampleRate(voicePath)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(p.binaryPath, "--model", voicePath, "--output_raw", "--quiet")
+	cmd.Stdin = strings.NewReader(text)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open piper output: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start piper: %w", err)
+	}
+
+	p.lock.Lock()
+	p.cmd = cmd
+	p.lock.Unlock()
+
+	// like the deepgram websocket, audio arrives asynchronously and is terminated with nil
+	go func() {
+		defer func() {
+			out <- nil
+		}()
+
+		audio, err := io.ReadAll(stdout)
+		if err != nil {
+			log.Error().Err(err).Msg("error reading piper output")
+			return
+		}
+		if err := cmd.Wait(); err != nil {
+			log.Error().Err(err).Msg("piper exited with error")
+			return
+		}
+
+		audio = resampleLinear16(audio, sampleRate, SAMPLE_RATE)
+		for start := 0; start < len(audio); start += chunkSize {
+			end := min(start+chunkSize, len(audio))
+			out <- audio[start:end]
+		}
+	}()
+
+	return nil
+}
+
+func (p *PiperSpeechSynthesizer) Disconnect() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	log.Info().Msg("disconnecting from piper TTS")
+	if p.cmd != nil {
+		// no-op if piper already finished
+		p.cmd.Process.Kill()
+		p.cmd = nil
+	}
+	return nil
+}
+
+// resampleLinear16 converts little endian 16 bit mono audio between sample rates with linear interpolation
+func resampleLinear16(audio []byte, fromRate int, toRate int) []byte {
+	if fromRate == toRate {
+		return audio
+	}
+
+	numSamples := len(audio) / 2
+	if numSamples == 0 {
+		return []byte{}
+	}
+	samples := make([]int16, numSamples)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(audio[i*2:]))
+	}
+
+	outSamples := numSamples * toRate / fromRate
+	resampled := make([]byte, 0, outSamples*2)
+	for i := 0; i < outSamples; i++ {
+		position := float64(i) * float64(fromRate) / float64(toRate)
+		index := int(position)
+		fraction := position - float64(index)
+
+		sample := float64(samples[index])
+		if index+1 < numSamples {
+			sample += (float64(samples[index+1]) - sample) * fraction
+		}
+		resampled = binary.LittleEndian.AppendUint16(resampled, uint16(int16(sample)))
+	}
+	return resampled
+}
@@ -0,0 +1,99 @@
+package piperspeaker
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupVoices(t *testing.T, script string, sampleRate string) (string, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake piper binary is a shell script")
+	}
+	dir := t.TempDir()
+	binaryPath := filepath.Join(dir, "piper")
+	assert.Nil(t, os.WriteFile(binaryPath, []byte("#!/bin/sh\ncat > /dev/null\n"+script), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "en_US-amy.onnx"), []byte{}, 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "en_US-amy.onnx.json"),
+		[]byte(`{"audio": {"sample_rate": `+sampleRate+`}}`), 0644))
+	return binaryPath, dir
+}
+
+func readAll(out chan []byte) []byte {
+	audio := make([]byte, 0)
+	for {
+		chunk := <-out
+		if chunk == nil {
+			return audio
+		}
+		audio = append(audio, chunk...)
+	}
+}
+
+func TestGenerateSpeech(t *testing.T) {
+	assert := assert.New(t)
+
+	binaryPath, voicesDir := setupVoices(t, `printf '\001\000\002\000'`, "16000")
+	synth := NewPiperSpeechSynthesizer(binaryPath, voicesDir, "en_US-amy")
+
+	// unknown deepgram model names fall back to the default voice
+	out := make(chan []byte, 5)
+	err := synth.GenerateSpeech("aura-asteria-en", "loud and clear", out)
+	assert.Nil(err)
+	assert.Equal([]byte{1, 0, 2, 0}, readAll(out))
+	assert.Nil(synth.Disconnect())
+}
+
+func TestGenerateSpeech_PiperFails(t *testing.T) {
+	assert := assert.New(t)
+
+	binaryPath, voicesDir := setupVoices(t, "exit 1\n", "16000")
+	synth := NewPiperSpeechSynthesizer(binaryPath, voicesDir, "en_US-amy")
+
+	// failure after startup still terminates the stream
+	out := make(chan []byte, 5)
+	err := synth.GenerateSpeech("en_US-amy", "loud and clear", out)
+	assert.Nil(err)
+	assert.Empty(readAll(out))
+}
+
+func TestGenerateSpeech_MissingVoice(t *testing.T) {
+	binaryPath, voicesDir := setupVoices(t, "", "16000")
+	synth := NewPiperSpeechSynthesizer(binaryPath, voicesDir, "en_GB-alan")
+
+	err := synth.GenerateSpeech("aura-asteria-en", "loud and clear", make(chan []byte, 5))
+	assert.NotNil(t, err)
+}
+
+func TestInstalledVoices(t *testing.T) {
+	_, voicesDir := setupVoices(t, "", "16000")
+	assert.Nil(t, os.WriteFile(filepath.Join(voicesDir, "en_GB-alan.onnx"), []byte{}, 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(voicesDir, "en_GB-alan.onnx.json"), []byte{}, 0644))
+	// no config, so piper can't use it
+	assert.Nil(t, os.WriteFile(filepath.Join(voicesDir, "en_US-joe.onnx"), []byte{}, 0644))
+
+	assert.Equal(t, []string{"en_US-amy", "en_GB-alan"}, InstalledVoices(voicesDir, "en_US-amy"))
+	assert.Equal(t, []string{"en_US-amy"}, InstalledVoices(filepath.Join(voicesDir, "missing"), "en_US-amy"))
+}
+
+func TestResampleLinear16(t *testing.T) {
+	assert := assert.New(t)
+
+	audio := make([]byte, 0)
+	for _, sample := range []int16{0, 100, 200, 300} {
+		audio = binary.LittleEndian.AppendUint16(audio, uint16(sample))
+	}
+
+	downsampled := resampleLinear16(audio, 32000, 16000)
+	assert.Equal(4, len(downsampled))
+	assert.Equal(int16(0), int16(binary.LittleEndian.Uint16(downsampled[0:])))
+	assert.Equal(int16(200), int16(binary.LittleEndian.Uint16(downsampled[2:])))
+
+	upsampled := resampleLinear16(audio, 16000, 32000)
+	assert.Equal(16, len(upsampled))
+	assert.Equal(int16(50), int16(binary.LittleEndian.Uint16(upsampled[2:])))
+}
@@ -0,0 +1,194 @@
+package speechcache
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	deepgramspeaker "github.com/ErikGoldman/DCSAtcOverhaul/pkg/deepgramSpeaker"
+	"github.com/rs/zerolog/log"
+)
+
+// Replies are mostly the same few phrases with a callsign, runway or heading in between: "hawg 3 1, runway
+// 04, cleared to land". Each part between the commas and full stops is synthesized and cached on its own, so
+// after the first time a pilot is cleared to land only their callsign and runway are new, and those are
+// cached too the next time around. The fragments are put back together with a short silence where the
+// commas and full stops were.
+
+// FRAGMENT_TIMEOUT is how long a fragment can take to synthesize before we give up on the phrase
+const FRAGMENT_TIMEOUT = 10 * time.Second
+
+// FRAGMENT_PAUSE is the silence between fragments
+const FRAGMENT_PAUSE = 200 * time.Millisecond
+
+// COMMON_FRAGMENTS are said often enough that they're worth synthesizing before anyone calls
+var COMMON_FRAGMENTS = []string{
+	"loud and clear",
+	"say again",
+	"unable to locate your aircraft",
+	"you are on the ground",
+	"you are airborne",
+	"hold short",
+	"hold position",
+	"cleared for takeoff",
+	"cleared to land",
+	"go around",
+	"taxi to parking",
+	"startup approved",
+	"contact tower",
+	"contact ground",
+	"traffic on the runway",
+	"nothing further",
+}
+
+var fragmentSeparator = regexp.MustCompile(`[,.;!?]+(\s+|$)`)
+
+// fragments splits the text where the speaker would pause
+func fragments(text string) []string {
+	parts := []string{}
+	for _, part := range fragmentSeparator.Split(text, -1) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// CachingSpeechSynthesizer speaks from the cache, only asking the synthesizer it wraps for fragments it hasn't
+// heard before. Like the synthesizers it wraps, it's only used for one phrase at a time.
+type CachingSpeechSynthesizer struct {
+	cache       *Cache
+	synthesizer deepgramspeaker.TextToSpeech
+	// how long to wait for the wrapped synthesizer to finish a fragment
+	timeout time.Duration
+	// linear16 silence sent after every fragment but the last
+	pause []byte
+
+	mu sync.Mutex
+	// closed by Disconnect to stop sending the phrase
+	stop chan struct{}
+}
+
+// NewCachingSpeechSynthesizer wraps a synthesizer that sends linear16 audio at the sample rate
+func NewCachingSpeechSynthesizer(cache *Cache, synthesizer deepgramspeaker.TextToSpeech, sampleRate int) *CachingSpeechSynthesizer {
+	return &CachingSpeechSynthesizer{
+		cache:       cache,
+		synthesizer: synthesizer,
+		timeout:     FRAGMENT_TIMEOUT,
+		pause:       make([]byte, int(float64(sampleRate)*FRAGMENT_PAUSE.Seconds())*2),
+	}
+}
+
+// GenerateSpeech gets every fragment of the text from the cache or the wrapped synthesizer, then sends their
+// audio in turn, with a pause between them, followed by nil. If any fragment can't be synthesized nothing is sent and the error is returned:
+// a taxi clearance without its "hold short" is worse than no reply at all.
+func (c *CachingSpeechSynthesizer) GenerateSpeech(model string, text string, out chan []byte) error {
+	audio := [][]byte{}
+	for i, fragment := range fragments(text) {
+		fragmentAudio, err := c.fragment(model, fragment)
+		if err != nil {
+			return fmt.Errorf("failed to synthesize %q of %q: %w", fragment, text, err)
+		}
+		if i > 0 {
+			audio[i-1] = append(audio[i-1], c.pause...)
+		}
+		audio = append(audio, fragmentAudio)
+	}
+
+	stop := make(chan struct{})
+	c.mu.Lock()
+	c.stop = stop
+	c.mu.Unlock()
+
+	go func() {
+		for _, chunk := range append(audio, nil) {
+			select {
+			case out <- chunk:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Disconnect stops sending the phrase. The wrapped synthesizer is disconnected after every fragment.
+func (c *CachingSpeechSynthesizer) Disconnect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stop != nil {
+		select {
+		case <-c.stop:
+		default:
+			close(c.stop)
+		}
+	}
+	return nil
+}
+
+// Warm synthesizes any fragments of the phrases that aren't cached yet
+func (c *CachingSpeechSynthesizer) Warm(model string, phrases []string) {
+	warmed := 0
+	for _, phrase := range phrases {
+		for _, fragment := range fragments(phrase) {
+			if _, ok := c.cache.Get(model, fragment); ok {
+				continue
+			}
+			if _, err := c.fragment(model, fragment); err != nil {
+				log.Warn().Err(err).Msgf("failed to synthesize %q", fragment)
+				continue
+			}
+			warmed++
+		}
+	}
+	if warmed > 0 {
+		log.Info().Msgf("cached %d phrases for %s", warmed, model)
+	}
+}
+
+// fragment is the audio for one fragment, from the cache if we have it
+func (c *CachingSpeechSynthesizer) fragment(model string, text string) ([]byte, error) {
+	if audio, ok := c.cache.Get(model, text); ok {
+		return audio, nil
+	}
+
+	// the synthesizer may still send nil when it's disconnected, so this mustn't block it
+	chunks := make(chan []byte, 5)
+	if err := c.synthesizer.GenerateSpeech(model, text, chunks); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := c.synthesizer.Disconnect(); err != nil {
+			log.Warn().Err(err).Msg("failed to disconnect from speech synthesizer")
+		}
+	}()
+
+	audio := []byte{}
+	timeout := time.After(c.timeout)
+	for {
+		select {
+		case chunk := <-chunks:
+			if chunk != nil {
+				audio = append(audio, chunk...)
+				continue
+			}
+			if len(audio) == 0 {
+				return nil, fmt.Errorf("no audio")
+			}
+			c.cache.Put(model, text, audio)
+			return audio, nil
+		case <-timeout:
+			// don't leave the synthesizer stuck sending the rest
+			go func() {
+				for chunk := range chunks {
+					if chunk == nil {
+						return
+					}
+				}
+			}()
+			return nil, fmt.Errorf("timed out after %s", c.timeout)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package speechcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const cacheFileSuffix = ".pcm"
+
+// Put writes to a temporary file first, so an interrupted write never leaves a truncated recording
+const tempFileSuffix = ".tmp"
+
+// Cache keeps synthesized speech on disk, keyed by voice and text, and throws away whatever was used longest
+// ago once it's over its size. A file's modification time is when it was last used, so the order survives a
+// restart. It's safe to share between synthesizers.
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu sync.Mutex
+	// most recently used at the front
+	recent  *list.List
+	entries map[string]*list.Element
+	size    int64
+}
+
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+// NewCache opens the cache in the directory, creating it if needed. Files left half written when we last
+// stopped are deleted.
+func NewCache(dir string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create speech cache %s: %w", dir, err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read speech cache %s: %w", dir, err)
+	}
+
+	type cachedFile struct {
+		key     string
+		size    int64
+		lastUse time.Time
+	}
+	cached := []cachedFile{}
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), tempFileSuffix) {
+			if err := os.Remove(filepath.Join(dir, file.Name())); err != nil {
+				log.Warn().Err(err).Msgf("failed to delete %s from speech cache", file.Name())
+			}
+			continue
+		}
+		if file.IsDir() || !strings.HasSuffix(file.Name(), cacheFileSuffix) {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		cached = append(cached, cachedFile{strings.TrimSuffix(file.Name(), cacheFileSuffix), info.Size(), info.ModTime()})
+	}
+	sort.Slice(cached, func(i, j int) bool {
+		return cached[i].lastUse.After(cached[j].lastUse)
+	})
+
+	c := &Cache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		recent:   list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+	for _, file := range cached {
+		c.entries[file.key] = c.recent.PushBack(&cacheEntry{key: file.key, size: file.size})
+		c.size += file.size
+	}
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+	log.Info().Msgf("speech cache %s has %d phrases, %d KB", dir, c.recent.Len(), c.size/1024)
+	return c, nil
+}
+
+func cacheKey(model string, text string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+cacheFileSuffix)
+}
+
+// Get is the audio for the text in the voice, if we have it
+func (c *Cache) Get(model string, text string) ([]byte, bool) {
+	key := cacheKey(model, text)
+	c.mu.Lock()
+	element, ok := c.entries[key]
+	if ok {
+		c.recent.MoveToFront(element)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	audio, err := os.ReadFile(c.path(key))
+	if err != nil {
+		log.Warn().Err(err).Msgf("dropping unreadable cached speech for %q", text)
+		c.remove(key, element)
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(c.path(key), now, now)
+	return audio, true
+}
+
+// Put stores the audio for the text in the voice
+func (c *Cache) Put(model string, text string, audio []byte) {
+	key := cacheKey(model, text)
+	// written to the side first so a crash can't leave half a phrase in the cache
+	temp, err := os.CreateTemp(c.dir, key+"-*"+tempFileSuffix)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to cache speech")
+		return
+	}
+	_, err = temp.Write(audio)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+
+	// renamed under the lock so evicting the old entry can't delete the new file
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		err = os.Rename(temp.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		log.Error().Err(err).Msg("failed to cache speech")
+		return
+	}
+	if element, ok := c.entries[key]; ok {
+		c.size -= element.Value.(*cacheEntry).size
+		c.recent.Remove(element)
+	}
+	c.entries[key] = c.recent.PushFront(&cacheEntry{key: key, size: int64(len(audio))})
+	c.size += int64(len(audio))
+	c.evict()
+}
+
+// Len is the number of phrases cached
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.recent.Len()
+}
+
+// remove drops the entry, unless it has been evicted or stored again since the caller looked it up
+func (c *Cache) remove(key string, element *list.Element) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[key] != element {
+		return
+	}
+	c.size -= element.Value.(*cacheEntry).size
+	c.recent.Remove(element)
+	delete(c.entries, key)
+	os.Remove(c.path(key))
+}
+
+// evict drops the least recently used phrases until the cache fits, the caller holds the lock
+func (c *Cache) evict() {
+	for c.size > c.maxBytes && c.recent.Len() > 0 {
+		entry := c.recent.Remove(c.recent.Back()).(*cacheEntry)
+		delete(c.entries, entry.key)
+		c.size -= entry.size
+		if err := os.Remove(c.path(entry.key)); err != nil && !os.IsNotExist(err) {
+			log.Warn().Err(err).Msg("failed to remove cached speech")
+		}
+	}
+}
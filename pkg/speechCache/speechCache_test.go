@@ -0,0 +1,213 @@
+package speechcache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSynthesizer speaks the text as its bytes and counts what it was asked for
+type fakeSynthesizer struct {
+	mu          sync.Mutex
+	synthesized []string
+	// never finishes the phrase
+	hang bool
+}
+
+func (f *fakeSynthesizer) GenerateSpeech(model string, text string, out chan []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.synthesized = append(f.synthesized, model+":"+text)
+	if text == "unpronounceable" {
+		return fmt.Errorf("can't say %s", text)
+	}
+	hang := f.hang
+	go func() {
+		out <- []byte(text)
+		if !hang {
+			out <- nil
+		}
+	}()
+	return nil
+}
+
+func (f *fakeSynthesizer) Disconnect() error {
+	return nil
+}
+
+func (f *fakeSynthesizer) calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.synthesized...)
+}
+
+// two samples of silence between fragments
+const testSampleRate = 10
+
+// speak collects everything said for the text, with silence as _
+func speak(t *testing.T, synthesizer *CachingSpeechSynthesizer, model string, text string) string {
+	out := make(chan []byte, 5)
+	assert.Nil(t, synthesizer.GenerateSpeech(model, text, out))
+	defer synthesizer.Disconnect()
+
+	said := ""
+	for {
+		select {
+		case audio := <-out:
+			if audio == nil {
+				return said
+			}
+			said += strings.ReplaceAll(string(audio), "\x00", "_") + "|"
+		case <-time.After(time.Second):
+			assert.Fail(t, "Expected the phrase to finish")
+			return said
+		}
+	}
+}
+
+func TestFragments(t *testing.T) {
+	assert.Equal(t, []string{"hawg 3 1", "runway 04", "cleared to land"}, fragments("hawg 3 1, runway 04, cleared to land"))
+	assert.Equal(t, []string{"good morning", "hawg 3 1", "loud and clear"}, fragments("good morning, hawg 3 1. loud and clear."))
+	assert.Equal(t, []string{"qnh 29.92"}, fragments("qnh 29.92"), "numbers aren't split")
+	assert.Empty(t, fragments(" , "))
+}
+
+func TestCachingSpeechSynthesizer_ReusesFragments(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), 1024)
+	assert.Nil(t, err)
+	fake := &fakeSynthesizer{}
+	synthesizer := NewCachingSpeechSynthesizer(cache, fake, testSampleRate)
+
+	assert.Equal(t, "hawg 3 1____|loud and clear|", speak(t, synthesizer, "aura-orion-en", "hawg 3 1, loud and clear"))
+	assert.Equal(t, "dude 1 1____|loud and clear|", speak(t, synthesizer, "aura-orion-en", "dude 1 1, loud and clear"))
+	assert.Equal(t, "hawg 3 1____|loud and clear|", speak(t, synthesizer, "aura-orion-en", "hawg 3 1, loud and clear"))
+	// another voice is another recording
+	assert.Equal(t, "loud and clear|", speak(t, synthesizer, "aura-asteria-en", "loud and clear"))
+
+	assert.Equal(t, []string{
+		"aura-orion-en:hawg 3 1",
+		"aura-orion-en:loud and clear",
+		"aura-orion-en:dude 1 1",
+		"aura-asteria-en:loud and clear",
+	}, fake.calls())
+	assert.Equal(t, 4, cache.Len())
+}
+
+func TestCachingSpeechSynthesizer_Failures(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), 1024)
+	assert.Nil(t, err)
+	fake := &fakeSynthesizer{}
+	synthesizer := NewCachingSpeechSynthesizer(cache, fake, testSampleRate)
+	synthesizer.timeout = 20 * time.Millisecond
+
+	// a phrase with a piece missing isn't said at all
+	out := make(chan []byte, 5)
+	assert.ErrorContains(t, synthesizer.GenerateSpeech("voice", "hawg 3 1, unpronounceable, say again", out), "unpronounceable")
+	assert.Empty(t, out)
+	assert.Equal(t, 1, cache.Len(), "what was synthesized is still worth keeping")
+
+	// nor is one the synthesizer never finishes, and nothing is kept
+	fake.mu.Lock()
+	fake.hang = true
+	fake.mu.Unlock()
+	assert.ErrorContains(t, synthesizer.GenerateSpeech("voice", "hawg 3 1, cleared to land", out), "timed out")
+	assert.Empty(t, out)
+	assert.Equal(t, 1, cache.Len())
+
+	// and the next phrase is unaffected
+	fake.mu.Lock()
+	fake.hang = false
+	fake.mu.Unlock()
+	assert.Equal(t, "hawg 3 1____|cleared to land|", speak(t, synthesizer, "voice", "hawg 3 1, cleared to land"))
+}
+
+func TestCache_RemoveKeepsNewerEntry(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), 1024)
+	assert.Nil(t, err)
+	cache.Put("voice", "one", []byte("old"))
+	element := cache.entries[cacheKey("voice", "one")]
+
+	// stored again while the old file was being read
+	cache.Put("voice", "one", []byte("new"))
+	cache.remove(cacheKey("voice", "one"), element)
+
+	audio, ok := cache.Get("voice", "one")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("new"), audio)
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), 10)
+	assert.Nil(t, err)
+	cache.Put("voice", "one", []byte("1111"))
+	cache.Put("voice", "two", []byte("2222"))
+	_, ok := cache.Get("voice", "one")
+	assert.True(t, ok)
+
+	cache.Put("voice", "three", []byte("3333"))
+	_, ok = cache.Get("voice", "two")
+	assert.False(t, ok, "least recently used")
+	audio, ok := cache.Get("voice", "one")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1111"), audio)
+	_, ok = cache.Get("voice", "three")
+	assert.True(t, ok)
+
+	files, err := os.ReadDir(cache.dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestCache_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewCache(dir, 10)
+	assert.Nil(t, err)
+	cache.Put("voice", "one", []byte("1111"))
+	cache.Put("voice", "two", []byte("2222"))
+	// one was used more recently, even though it was stored first
+	past := time.Now().Add(-time.Hour)
+	assert.Nil(t, os.Chtimes(cache.path(cacheKey("voice", "two")), past, past))
+
+	// a smaller cache keeps what was used last
+	cache, err = NewCache(dir, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cache.Len())
+	audio, ok := cache.Get("voice", "one")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1111"), audio)
+}
+
+func TestCache_DeletesInterruptedWrites(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewCache(dir, 1024)
+	assert.Nil(t, err)
+	cache.Put("voice", "one", []byte("1111"))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, cacheKey("voice", "two")+"-123.tmp"), []byte("22"), 0o644))
+
+	cache, err = NewCache(dir, 1024)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, cache.Len())
+	files, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestCachingSpeechSynthesizer_Warm(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), 1024)
+	assert.Nil(t, err)
+	fake := &fakeSynthesizer{}
+	synthesizer := NewCachingSpeechSynthesizer(cache, fake, testSampleRate)
+
+	synthesizer.Warm("voice", []string{"loud and clear", "anapa tower", "hold short, cleared for takeoff"})
+	synthesizer.Warm("voice", []string{"loud and clear"})
+	assert.Equal(t, []string{"voice:loud and clear", "voice:anapa tower", "voice:hold short", "voice:cleared for takeoff"}, fake.calls())
+
+	assert.Equal(t, "anapa tower____|loud and clear|", speak(t, synthesizer, "voice", "anapa tower, loud and clear"))
+	assert.Len(t, fake.calls(), 4)
+}
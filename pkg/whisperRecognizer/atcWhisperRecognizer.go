@@ -0,0 +1,115 @@
+package whisperRecognizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/dharmab/skyeye/pkg/pcm"
+	"github.com/dharmab/skyeye/pkg/recognizer"
+	"github.com/rs/zerolog/log"
+)
+
+// SRS hands us 16kHz mono audio, which is also what whisper expects
+const sampleRate = 16000
+
+// biases whisper towards aviation phraseology instead of plain english
+const atcPrompt = "Anapa tower, Uzi two-one, radio check. Hawg three-one, flight of two, request taxi. Runway two-two, cleared for takeoff."
+
+// AtcWhisperRecognizer runs the whisper.cpp command line binary built by `make whisper-main`
+// against a local ggml model, so no network connection is needed.
+type AtcWhisperRecognizer struct {
+	binaryPath string
+	modelPath  string
+	threads    int
+}
+
+func NewAtcWhisperRecognizer(binaryPath string, modelPath string, threads int) recognizer.Recognizer {
+	return &AtcWhisperRecognizer{
+		binaryPath: binaryPath,
+		modelPath:  modelPath,
+		threads:    threads,
+	}
+}
+
+func (r *AtcWhisperRecognizer) Recognize(ctx context.Context, pcmData []float32, enableTranscriptionLogging bool) (string, error) {
+	wavFile, err := os.CreateTemp("", "atc-whisper-*.wav")
+	if err != nil {
+		return "", fmt.Errorf("failed to create WAV file: %w", err)
+	}
+	defer os.Remove(wavFile.Name())
+
+	_, err = wavFile.Write(pcmToWav(pcmData))
+	wavFile.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to write WAV file: %w", err)
+	}
+
+	args := []string{
+		"--model", r.modelPath,
+		"--file", wavFile.Name(),
+		"--language", "en",
+		"--prompt", atcPrompt,
+		"--no-timestamps",
+		"--no-prints",
+	}
+	if r.threads > 0 {
+		args = append(args, "--threads", fmt.Sprintf("%d", r.threads))
+	}
+
+	log.Info().Msgf("Sending %d samples to whisper", len(pcmData))
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.binaryPath, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("whisper failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	log.Info().Msgf("Whisper completed")
+
+	return parseTranscript(stdout.String()), nil
+}
+
+// whisper prints one line per segment; join them back into a single utterance
+func parseTranscript(output string) string {
+	segments := make([]string, 0)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "[BLANK_AUDIO]" {
+			continue
+		}
+		segments = append(segments, line)
+	}
+	return strings.Join(segments, " ")
+}
+
+func pcmToWav(pcmData []float32) []byte {
+	samples := pcm.F32toS16LE(pcmData)
+
+	buf := new(bytes.Buffer)
+
+	// Write WAV header
+	binary.Write(buf, binary.LittleEndian, []byte("RIFF"))
+	binary.Write(buf, binary.LittleEndian, int32(36+len(samples)*2)) // File size
+	binary.Write(buf, binary.LittleEndian, []byte("WAVE"))
+	binary.Write(buf, binary.LittleEndian, []byte("fmt "))
+	binary.Write(buf, binary.LittleEndian, int32(16)) // Size of fmt chunk
+	binary.Write(buf, binary.LittleEndian, int16(1))  // Audio format (PCM)
+	binary.Write(buf, binary.LittleEndian, int16(1))  // Number of channels
+	binary.Write(buf, binary.LittleEndian, int32(sampleRate))
+	binary.Write(buf, binary.LittleEndian, int32(sampleRate*2)) // Byte rate
+	binary.Write(buf, binary.LittleEndian, int16(2))            // Block align
+	binary.Write(buf, binary.LittleEndian, int16(16))           // Bits per sample
+	binary.Write(buf, binary.LittleEndian, []byte("data"))
+	binary.Write(buf, binary.LittleEndian, int32(len(samples)*2)) // Size of data chunk
+
+	binary.Write(buf, binary.LittleEndian, samples)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,79 @@
+package whisperRecognizer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFakeWhisper(t *testing.T, script string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake whisper binary is a shell script")
+	}
+	path := filepath.Join(t.TempDir(), "whisper")
+	err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755)
+	assert.Nil(t, err)
+	return path
+}
+
+func TestWhisperRecognizer(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name         string
+		script       string
+		expectedText string
+		shouldFail   bool
+	}{
+		{
+			name:         "single segment",
+			script:       "echo ' anapa tower uzi two one radio check'\n",
+			expectedText: "anapa tower uzi two one radio check",
+		},
+		{
+			name:         "multiple segments",
+			script:       "echo ' anapa tower'\necho ''\necho ' uzi two one radio check'\n",
+			expectedText: "anapa tower uzi two one radio check",
+		},
+		{
+			name:         "blank audio",
+			script:       "echo ' [BLANK_AUDIO]'\n",
+			expectedText: "",
+		},
+		{
+			name:       "binary fails",
+			script:     "echo 'failed to load model' >&2\nexit 1\n",
+			shouldFail: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewAtcWhisperRecognizer(writeFakeWhisper(t, tt.script), "model.bin", 0)
+
+			text, err := r.Recognize(context.Background(), []float32{0, 0.5, -0.5}, false)
+			if tt.shouldFail {
+				assert.NotNil(err)
+				assert.Contains(err.Error(), "failed to load model")
+				return
+			}
+
+			assert.Nil(err)
+			assert.Equal(tt.expectedText, text)
+		})
+	}
+}
+
+func TestPcmToWav(t *testing.T) {
+	assert := assert.New(t)
+
+	wav := pcmToWav([]float32{0, 1, -1})
+	assert.Equal(44+3*2, len(wav))
+	assert.Equal("RIFF", string(wav[0:4]))
+	assert.Equal("WAVE", string(wav[8:12]))
+	assert.Equal("data", string(wav[36:40]))
+}